@@ -181,6 +181,8 @@ func Test_Builder(t *testing.T) {
 func BenchmarkBuildMatch(b *testing.B) {
 	var s string
 
+	b.ReportAllocs()
+
 	for range b.N {
 		s, _ = NewBuilder().
 			Input(Var("i")).
@@ -202,6 +204,8 @@ func BenchmarkBuildMatch(b *testing.B) {
 func BenchmarkBuildMarkup(b *testing.B) {
 	var s string
 
+	b.ReportAllocs()
+
 	for range b.N {
 		s, _ = NewBuilder().
 			OpenMarkup(
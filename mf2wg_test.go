@@ -19,84 +19,7 @@ var failing []string
 func init() {
 	//nolint:lll
 	failing = []string{
-		"TestMF2WG/Currency_function/.local_$n_=_{42_:integer}_{{{$n_:currency_currency=EUR}}}",
-		"TestMF2WG/Currency_function/.local_$n_=_{42_:number}_{{{$n_:currency_currency=EUR}}}",
-		"TestMF2WG/Currency_function/.local_$n_=_{42_:number}_{{{$n_:currency}}}",
-		"TestMF2WG/Currency_function/.local_$n_=_{42_:currency_currency=EUR}_{{{$n_:currency}}}",
-		"TestMF2WG/Currency_function/{foo_:currency}",
-		"TestMF2WG/Currency_function/{:currency}",
-		"TestMF2WG/Currency_function/{42_:currency}",
-		"TestMF2WG/Currency_function/{$x_:currency_currency=EUR}",
-		"TestMF2WG/Currency_function/{42_:currency_currency=EUR_fractionDigits=2}",
-		"TestMF2WG/Currency_function/{42_:currency_currency=EUR_fractionDigits=auto}",
-		"TestMF2WG/Currency_function/{42_:currency_currency=EUR}",
-
-		"TestMF2WG/Integer_function/.local_$bad_=_{exact}_{{variable_select_{1_:integer_select=$bad}}}",
-		"TestMF2WG/Integer_function/.local_$sel_=_{1_:integer_select=exact}_.local_$bad_=_{$sel_:integer}_.match_$bad_1_{{ONE}}_*_{{operand_select_{$bad}}}",
-		"TestMF2WG/Integer_function/.local_$sel_=_{1_:integer_select=$bad}_.match_$sel_1_{{ONE}}_*_{{variable_select_{$sel}}}",
-		"TestMF2WG/Integer_function/.local_$x_=_{1.25_:integer}_.local_$y_=_{$x_:number}_{{{$y}}}",
-		"TestMF2WG/Integer_function/variable_select_{1_:integer_select=$bad}",
-
-		"TestMF2WG/Offset_function/.local_$x_=_{52_:number_signDisplay=always}_{{{$x_:offset_subtract=10}}}",
-		"TestMF2WG/Offset_function/.local_$x_=_{1_:offset_add=1}_.match_$x_1_{{=1}}_2_{{=2}}_*_{{other}}",
-		"TestMF2WG/Offset_function/{:offset_add=13}",
-		"TestMF2WG/Offset_function/{52_:offset_subtract=10}",
-		"TestMF2WG/Offset_function/{$x_:offset_subtract=10}",
-		"TestMF2WG/Offset_function/{$x_:offset_add=1}",
-		"TestMF2WG/Offset_function/.local_$x_=_{10_:integer}_.local_$y_=_{$x_:offset_subtract=6}_.match_$y_10_{{=10}}_4_{{=4}}_*_{{other}}",
-		"TestMF2WG/Offset_function/.local_$x_=_{41_:integer_signDisplay=always}_{{{$x_:offset_add=1}}}",
-		"TestMF2WG/Offset_function/{42_:offset}",
-		"TestMF2WG/Offset_function/{foo_:offset_add=13}",
-		"TestMF2WG/Offset_function/{42_:offset_subtract=foo}",
-		"TestMF2WG/Offset_function/{41_:offset_add=1_foo=13}",
-		"TestMF2WG/Offset_function/{42_:offset_add=foo}",
-		"TestMF2WG/Offset_function/{42_:offset_foo=13}",
-		"TestMF2WG/Offset_function/{42_:offset_add=13_subtract=13}",
-		"TestMF2WG/Offset_function/{41_:offset_add=1}",
-
-		"TestMF2WG/Percent_function/{:percent}",
-		"TestMF2WG/Percent_function/{0.12345678_:percent_maximumFractionDigits=1}",
-		"TestMF2WG/Percent_function/.local_$n_=_{0.42_:number}_{{{$n_:percent}}}",
-		"TestMF2WG/Percent_function/.local_$n_=_{0.01_:percent}_{{{$n_:percent}}}",
-		"TestMF2WG/Percent_function/{$x_:percent}",
-		"TestMF2WG/Percent_function/.local_$n_=_{42_:integer}_{{{$n_:percent}}}",
-		"TestMF2WG/Percent_function/.input_{$n_:percent}_.match_$n_one_{{one}}_*_{{other}}#01",
-		"TestMF2WG/Percent_function/{0.12_:percent_minimumSignificantDigits=1}",
-		"TestMF2WG/Percent_function/{foo_:percent}",
-		"TestMF2WG/Percent_function/.input_{$n_:percent}_.match_$n_one_{{one}}_*_{{other}}",
-		"TestMF2WG/Percent_function/{1_:percent}",
-		"TestMF2WG/Percent_function/{0.12345678_:percent}",
-		"TestMF2WG/Percent_function/{0.12_:percent_minimumFractionDigits=1}",
-
 		"TestMF2WG/Number_function/.local_$foo_=_{$bar_:number}_{{bar_{$foo}}}#01",
-		"TestMF2WG/Number_function/.local_$bad_=_{exact}_{{variable_select_{1_:number_select=$bad}}}",
-		"TestMF2WG/Number_function/.local_$sel_=_{1_:number_select=exact}_.local_$bad_=_{$sel_:number}_.match_$bad_1_{{ONE}}_*_{{operand_select_{$bad}}}",
-		"TestMF2WG/Number_function/.local_$sel_=_{1_:number_select=$bad}_.match_$sel_1_{{ONE}}_*_{{variable_select_{$sel}}}",
-		"TestMF2WG/Number_function/variable_select_{1_:number_select=$bad}",
-
-		"TestMF2WG/u:_Options/أهلاً_{بالعالم_:string}",
-		"TestMF2WG/u:_Options/hello_{world_:string_u:dir=auto}",
-		"TestMF2WG/u:_Options/أهلاً_{world_:string_u:dir=ltr}",
-		"TestMF2WG/u:_Options/أهلاً_{بالعالم_:string_u:dir=auto}",
-		"TestMF2WG/u:_Options/أهلاً_{بالعالم_:string_u:dir=rtl}",
-		"TestMF2WG/u:_Options/hello_{world_:string_u:dir=ltr_u:id=foo}",
-		"TestMF2WG/u:_Options/hello_{4.2_:number_u:locale=fr}",
-		"TestMF2WG/u:_Options/{#tag_u:dir=rtl_u:locale=ar}content{/ns:tag}",
-		"TestMF2WG/u:_Options/{#tag_u:dir=rtl}content{/ns:tag}",
-		"TestMF2WG/u:_Options/hello_{world_:string_u:dir=rtl}",
-		"TestMF2WG/u:_Options/.local_$world_=_{world_:string_u:dir=ltr_u:id=foo}_{{hello_{$world}}}",
-
-		"TestMF2WG/Bidi_support/.local_$\\u200efoo\\u200f_=_{3}_{{{$\\u200efoo\\u200f}}}",
-		"TestMF2WG/Bidi_support/.local_$\\u200efoo\\u200f_=_{5}_{{{$foo}}}",
-		"TestMF2WG/Bidi_support/.local_$\\u061cfoo_=_{1}_{{_{$\\u061cfoo}_}}",
-		"TestMF2WG/Bidi_support/.local_$foo_=_{4}_{{{$\\u200efoo\\u200f}}}",
-		"TestMF2WG/Bidi_support/.local_$x_=_{1}_{{_{\\u200e_$x_\\u200f}_}}",
-		"TestMF2WG/Bidi_support/.local_$x_=_{1_:number}.match_$x\\u061c1_{{one}}_*_{{other}}",
-		"TestMF2WG/Bidi_support/.local_$x_=_{1_:number}.match_$x\\u061c1_{{one}}*_{{other}}",
-		"TestMF2WG/Bidi_support/.local_$x_=_{1}_\\u200f_{{_{$x}}}",
-		"TestMF2WG/Bidi_support/.local_$x_=_{1}_{{_{$x}}}_\\u2066",
-		"TestMF2WG/Bidi_support/{\\u200e_hello_\\u200f}",
-		"TestMF2WG/Bidi_support/\\u200e_.local_$x_=_{1}_{{_{$x}}}",
 	}
 }
 
@@ -170,6 +93,10 @@ func run(t *testing.T, test Test) {
 		options = append(options, template.WithLocale(*test.Locale))
 	}
 
+	if test.BidiIsolation == "default" {
+		options = append(options, template.WithBidiIsolation(template.BidiIsolationDefault))
+	}
+
 	t.Log(cmp.Or(test.Description, "no test description"))
 
 	templ, err := template.New(options...).Parse(test.Src)
@@ -250,12 +177,17 @@ func (t Test) Apply(defaultProperties DefaultTestProperties) Test {
 		t.Locale = defaultProperties.Locale
 	}
 
+	if t.BidiIsolation == "" {
+		t.BidiIsolation = defaultProperties.BidiIsolation
+	}
+
 	return t
 }
 
 type DefaultTestProperties struct {
-	Locale    *language.Tag `json:"locale"`
-	ExpErrors []Error       `json:"expErrors"`
+	Locale        *language.Tag `json:"locale"`
+	BidiIsolation string        `json:"bidiIsolation"`
+	ExpErrors     []Error       `json:"expErrors"`
 }
 
 type Error struct {
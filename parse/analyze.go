@@ -0,0 +1,120 @@
+package parse
+
+import "slices"
+
+// Info is the static information about the message, see [Analyze].
+type Info struct {
+	// Inputs are the input variables in the order of the first use. The input variable is
+	// either declared with .input or used without declaration.
+	Inputs []Variable
+	// DeclaredInputs are the input variables declared with .input, in the source order.
+	DeclaredInputs []Variable
+	// ImplicitInputs are the input variables used without .input declaration, in the order of the first use.
+	ImplicitInputs []Variable
+	// Locals are the variables declared with .local.
+	Locals []Variable
+	// Selectors are the selector variables of the matcher.
+	Selectors []Variable
+	// Functions are the function calls in the source order.
+	Functions []FunctionInfo
+	// Markup are the distinct markup identifiers in the order of the first use, e.g. "b" or "ns:b".
+	Markup []string
+}
+
+// FunctionInfo is the function call in the message.
+type FunctionInfo struct {
+	// Operand is the variable or literal the function is called with, nil without operand.
+	Operand Value
+	// Name is the function identifier, e.g. "number" or "ns:fn".
+	Name string
+	// Options are the option names in the source order.
+	Options []string
+}
+
+/*
+Analyze returns the static information about the message, e.g. to check that the call site
+provides all input variables the message requires.
+
+Example:
+
+	tree, _ := parse.Parse(".input {$count :number} .local $n = {$count :integer} {{{$name} has {$n} items}}")
+	info := parse.Analyze(tree)
+
+	fmt.Println(info.Inputs)         // [count name]
+	fmt.Println(info.DeclaredInputs) // [count]
+	fmt.Println(info.ImplicitInputs) // [name]
+	fmt.Println(info.Locals)         // [n]
+*/
+func Analyze(tree AST) Info {
+	var info Info
+
+	if tree.Message == nil {
+		return info
+	}
+
+	a := analyzer{info: &info}
+	Walk(a, tree.Message)
+
+	for _, v := range info.Inputs {
+		if !slices.Contains(info.DeclaredInputs, v) {
+			info.ImplicitInputs = append(info.ImplicitInputs, v)
+		}
+	}
+
+	return info
+}
+
+type analyzer struct {
+	info *Info
+}
+
+func (a analyzer) Visit(node Node) Visitor {
+	switch n := node.(type) {
+	case LocalDeclaration:
+		// the expression is analyzed before the variable is declared
+		Walk(a, n.Expression)
+		appendUnique(&a.info.Locals, n.Variable)
+
+		return nil
+	case Variable:
+		if !slices.Contains(a.info.Locals, n) {
+			appendUnique(&a.info.Inputs, n)
+		}
+	case Matcher:
+		a.info.Selectors = append(a.info.Selectors, n.Selectors...)
+	case InputDeclaration:
+		if v, ok := n.Operand.(Variable); ok {
+			appendUnique(&a.info.DeclaredInputs, v)
+		}
+
+		a.function(Expression(n))
+	case Expression:
+		a.function(n)
+	case Markup:
+		appendUnique(&a.info.Markup, n.Identifier.String())
+	}
+
+	return a
+}
+
+// function adds the function call of the expression, if any.
+func (a analyzer) function(expr Expression) {
+	function, ok := expr.Annotation.(Function)
+	if !ok {
+		return
+	}
+
+	info := FunctionInfo{Operand: expr.Operand, Name: function.Identifier.String()}
+
+	for _, option := range function.Options {
+		info.Options = append(info.Options, option.Identifier.String())
+	}
+
+	a.info.Functions = append(a.info.Functions, info)
+}
+
+func appendUnique[T comparable](s *[]T, v T) {
+	if !slices.Contains(*s, v) {
+		*s = append(*s, v)
+	}
+}
@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  Info
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  Info{},
+		},
+		{
+			name:  "simple message",
+			input: "Hello, {$name :string} {#b}{$name}{/b} {:now}!",
+			want: Info{
+				Inputs:         []Variable{"name"},
+				ImplicitInputs: []Variable{"name"},
+				Functions: []FunctionInfo{
+					{Operand: Variable("name"), Name: "string"},
+					{Name: "now"},
+				},
+				Markup: []string{"b"},
+			},
+		},
+		{
+			name: "complex message",
+			input: ".input {$count :number minimumFractionDigits=$digits} " +
+				".local $n = {$count :integer select=exact} " +
+				".input {$gender :string} " +
+				".match $n $gender " +
+				"one * {{{#ns:b}{$name}{/ns:b} has {|1| :number} item}} " +
+				"* * {{{$name} has {$n} items}}",
+			want: Info{
+				Inputs:         []Variable{"count", "digits", "gender", "name"},
+				DeclaredInputs: []Variable{"count", "gender"},
+				ImplicitInputs: []Variable{"digits", "name"},
+				Locals:         []Variable{"n"},
+				Selectors:      []Variable{"n", "gender"},
+				Functions: []FunctionInfo{
+					{Operand: Variable("count"), Name: "number", Options: []string{"minimumFractionDigits"}},
+					{Operand: Variable("count"), Name: "integer", Options: []string{"select"}},
+					{Operand: Variable("gender"), Name: "string"},
+					{Operand: QuotedLiteral("1"), Name: "number"},
+				},
+				Markup: []string{"ns:b"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := Analyze(tree); !reflect.DeepEqual(test.want, got) {
+				t.Errorf("want\n%+v\ngot\n%+v", test.want, got)
+			}
+		})
+	}
+}
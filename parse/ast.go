@@ -25,7 +25,13 @@ Example:
 
 	fmt.Print(ast) // Hello, { $variable } World!
 */
-func (a AST) String() string { return a.Message.String() }
+func (a AST) String() string {
+	if a.Message == nil { // empty message
+		return ""
+	}
+
+	return a.Message.String()
+}
 
 // --------------------------------Interfaces----------------------------------
 //
@@ -83,6 +89,35 @@ type VariantKey interface {
 	variantKey()
 }
 
+// ---------------------------------Span-------------------------------------
+
+// Position is a position in the parsed input.
+type Position struct {
+	Offset int // byte offset, starts at 0
+	Line   int // line number, starts at 1
+	Column int // column number in characters, starts at 1
+}
+
+// String returns the position in the "line:column" format.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the range of the node in the parsed input, the end is exclusive.
+//
+// The span is set by the parser for the struct nodes. It is zero for the nodes
+// created by other means, e.g. builder. Text, literals, variables and patterns are
+// plain values, their spans are recorded with [RecordSpans].
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// IsZero returns true if the span is not set.
+func (s Span) IsZero() bool {
+	return s == Span{}
+}
+
 // ---------------------------------Types------------------------------------
 //
 // Here we define the types that implement the interfaces defined above.
@@ -102,10 +137,16 @@ func (m SimpleMessage) message() {}
 type ComplexMessage struct {
 	ComplexBody  ComplexBody   // Matcher or QuotedPattern
 	Declarations []Declaration // Optional: InputDeclaration, LocalDeclaration or ReservedStatement
+	Span         Span
 }
 
 // String returns MF2 formatted string.
 func (m ComplexMessage) String() string {
+	// the complex body is missing in the partial AST, see [Recover]
+	if m.ComplexBody == nil {
+		return sliceToString(m.Declarations, "\n")
+	}
+
 	if len(m.Declarations) == 0 {
 		return m.ComplexBody.String()
 	}
@@ -146,6 +187,7 @@ type Expression struct {
 	Operand    Value       // Literal or Variable
 	Annotation Annotation  // Function, PrivateUseAnnotation or ReservedAnnotation
 	Attributes []Attribute // Optional
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -208,9 +250,10 @@ func (NameLiteral) variantKey() {}
 
 // --------------------------------Annotation----------------------------------
 
-type Function struct {
+type Function struct { //nolint:govet // the identifier first for readability
 	Identifier Identifier
 	Options    []Option // Optional
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -240,6 +283,7 @@ func (InputDeclaration) declaration() {}
 type LocalDeclaration struct {
 	Variable   Variable
 	Expression Expression
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -253,7 +297,9 @@ func (LocalDeclaration) declaration() {}
 // --------------------------------VariantKey----------------------------------
 
 // CatchAllKey is a special key, that matches any value.
-type CatchAllKey struct{}
+type CatchAllKey struct {
+	Span Span
+}
 
 // String returns MF2 formatted string.
 func (k CatchAllKey) String() string {
@@ -278,6 +324,7 @@ func (QuotedPattern) complexBody() {}
 type Matcher struct {
 	Selectors []Variable // At least one
 	Variants  []Variant  // At least one
+	Span      Span
 }
 
 // String returns MF2 formatted string.
@@ -309,6 +356,7 @@ type Identifier struct {
 
 	Namespace string // Optional
 	Name      string
+	Span      Span
 }
 
 // String returns MF2 formatted string.
@@ -325,6 +373,7 @@ type Variant struct {
 
 	Keys          []VariantKey // At least one: Literal or CatchAllKey
 	QuotedPattern QuotedPattern
+	Span          Span
 }
 
 // String returns MF2 formatted string.
@@ -337,6 +386,7 @@ type Option struct {
 
 	Value      Value // Literal or Variable
 	Identifier Identifier
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -353,13 +403,14 @@ const (
 	SelfClose
 )
 
-type Markup struct {
+type Markup struct { //nolint:govet // the identifier first for readability
 	PatternPart
 
 	Identifier Identifier
 	Options    []Option    // Optional. Options for Identifier, only allowed when markup-open.
 	Attributes []Attribute // Optional
 	Typ        MarkupType
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -407,6 +458,7 @@ type Attribute struct {
 
 	Value      Value // Optional: Literal or Variable
 	Identifier Identifier
+	Span       Span
 }
 
 // String returns MF2 formatted string.
@@ -61,6 +61,8 @@ one * {{one other}}
 
 	var result string
 
+	b.ReportAllocs()
+
 	for range b.N {
 		result = tree.String()
 	}
@@ -0,0 +1,101 @@
+package parse
+
+import (
+	"flag"
+	"runtime"
+	"testing"
+)
+
+// benchmarks is the corpus of the representative messages. Run "go test -run=^$ -bench=. -benchmem ./..."
+// to measure the performance, the allocations are limited by [TestAllocationBudget].
+var benchmarks = []struct {
+	name, text string
+	// allocs is the maximum number of allocations to parse the message.
+	allocs float64
+}{
+	{
+		name:   "simple text",
+		text:   "Hello, World! This is a plain message without any placeholders, the most common kind of message.",
+		allocs: 14,
+	},
+	{
+		name: "placeholders",
+		text: "Dear {$title} {$firstName} {$lastName}, {$sender} shared {$count} files " +
+			"from {$folder} with {$recipient} on {$date} at {$time} via {$app}, see {|https://example.com|}.",
+		allocs: 80,
+	},
+	{
+		name: "match",
+		text: `.input {$gender :string}
+.input {$count :integer}
+.input {$rank :number select=ordinal}
+.match $gender $count $rank
+female 0 one {{She has no items and is {$rank}st}}
+female one one {{She has one item and is {$rank}st}}
+female * one {{She has {$count} items and is {$rank}st}}
+female * two {{She has {$count} items and is {$rank}nd}}
+female * * {{She has {$count} items and is {$rank}th}}
+male 0 one {{He has no items and is {$rank}st}}
+male one one {{He has one item and is {$rank}st}}
+male * one {{He has {$count} items and is {$rank}st}}
+male * two {{He has {$count} items and is {$rank}nd}}
+male * * {{He has {$count} items and is {$rank}th}}
+* 0 * {{They have no items and are {$rank}th}}
+* one * {{They have one item and are {$rank}th}}
+* * * {{They have {$count} items and are {$rank}th}}`,
+		allocs: 320,
+	},
+	{
+		name: "markup",
+		text: "{#p}{#b}Bold{/b}, {#i}italic {#u}and underlined{/u}{/i} text with " +
+			"{#a href=|https://example.com| target=_blank @rel=nofollow}a link{/a}{#br/}" +
+			"{#span class=note}{#em}note{/em}: {$note}{/span}{/p}",
+		allocs: 95,
+	},
+	{
+		name: "formatting",
+		text: "{$amount :number minimumFractionDigits=2} ({$ratio :percent}) paid {$price :currency currency=EUR} " +
+			"on {$date :datetime dateStyle=long timeStyle=short}, due {$date :date style=medium} {$date :time}",
+		allocs: 75,
+	},
+}
+
+func BenchmarkCorpus(b *testing.B) {
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			var tree AST
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				tree, _ = Parse(bench.text)
+			}
+
+			runtime.KeepAlive(tree)
+		})
+	}
+}
+
+// budget enables [TestAllocationBudget]. The allocations depend on the versions of Go and the dependencies,
+// the budgets are checked on demand, e.g. "go test -run=TestAllocationBudget ./parse ./template -budget".
+var budget = flag.Bool("budget", false, "check the allocation budgets of the benchmark corpus")
+
+//nolint:paralleltest // testing.AllocsPerRun panics in the parallel tests
+func TestAllocationBudget(t *testing.T) {
+	if !*budget {
+		t.Skip("run with -budget to check the allocation budgets")
+	}
+
+	for _, bench := range benchmarks {
+		t.Run(bench.name, func(t *testing.T) {
+			if _, err := Parse(bench.text); err != nil {
+				t.Fatal(err)
+			}
+
+			allocs := testing.AllocsPerRun(10, func() { _, _ = Parse(bench.text) })
+			if allocs > bench.allocs {
+				t.Errorf("want at most %v allocations, got %v", bench.allocs, allocs)
+			}
+		})
+	}
+}
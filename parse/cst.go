@@ -0,0 +1,291 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenKind is the kind of the token in the concrete syntax tree.
+type TokenKind string
+
+// Kinds of the tokens.
+const (
+	TokenText               TokenKind = "text"
+	TokenWhitespace         TokenKind = "whitespace" // whitespace and bidi marks
+	TokenVariable           TokenKind = "variable"   // including "$"
+	TokenFunction           TokenKind = "function"   // including ":"
+	TokenOption             TokenKind = "option"
+	TokenAttribute          TokenKind = "attribute" // including "@"
+	TokenOperator           TokenKind = "operator"
+	TokenQuotedLiteral      TokenKind = "quoted literal" // including "|"
+	TokenUnquotedLiteral    TokenKind = "unquoted literal"
+	TokenCatchAllKey        TokenKind = "catch all key"
+	TokenExpressionOpen     TokenKind = "expression open"
+	TokenExpressionClose    TokenKind = "expression close"
+	TokenMarkupOpen         TokenKind = "markup open"  // including "#"
+	TokenMarkupClose        TokenKind = "markup close" // including "/", or "/" of the standalone markup
+	TokenQuotedPatternOpen  TokenKind = "quoted pattern open"
+	TokenQuotedPatternClose TokenKind = "quoted pattern close"
+	TokenInputKeyword       TokenKind = "input keyword" // including "."
+	TokenLocalKeyword       TokenKind = "local keyword" // including "."
+	TokenMatchKeyword       TokenKind = "match keyword" // including "."
+)
+
+// CST is the lossless concrete syntax tree of a MessageFormat 2.0 message. Unlike [AST.String],
+// [CST.String] returns the original input byte-for-byte, with the original whitespace,
+// quoting and escapes, except the edited tokens and the replaced subtrees.
+//
+// Example:
+//
+//	// rename $count to $total, the rest of the message is not reformatted
+//	cst, _ := parse.ParseCST(".input {$count   :number}\n{{You have {$count} items}}")
+//
+//	for _, token := range cst.Root.Tokens() {
+//		if token.Kind == parse.TokenVariable && token.Node == parse.Variable("count") {
+//			token.Text = "$total"
+//		}
+//	}
+//
+//	fmt.Println(cst) // .input {$total   :number}
+//	                 // {{You have {$total} items}}
+type CST struct {
+	Root *CSTNode
+}
+
+// String returns the source text of the message.
+func (c *CST) String() string {
+	if c.Root == nil {
+		return ""
+	}
+
+	return c.Root.String()
+}
+
+// AST parses the source text of the edited concrete syntax tree and returns the AST.
+func (c *CST) AST() (AST, error) {
+	return Parse(c.String())
+}
+
+// CSTNode is the node of the concrete syntax tree, either a token or a subtree.
+//
+// The subtrees are the messages, declarations, expressions, markup, functions, options,
+// attributes, matchers and variants. Their children are the tokens and the nested subtrees
+// in the source order. The identifiers, keys, variables, literals and text are the tokens.
+type CSTNode struct {
+	// The AST node of the subtree or the replacement, see [CSTNode.Replace].
+	// For the tokens, the variable, literal, text or nil.
+	Node Node
+	// The kind of the token, empty for the subtrees.
+	Kind TokenKind
+	// The source text of the token or the replacement node.
+	Text string
+	// The tokens and the subtrees in the source order, nil for the tokens.
+	Children []*CSTNode
+	// The span in the parsed input, not updated on edits.
+	Span Span
+}
+
+// String returns the source text of the node.
+func (n *CSTNode) String() string {
+	if n.Children == nil {
+		return n.Text
+	}
+
+	var sb strings.Builder
+
+	for _, child := range n.Children {
+		sb.WriteString(child.String())
+	}
+
+	return sb.String()
+}
+
+// Replace replaces the node with the formatted AST node, e.g. to rewrite the whole expression.
+func (n *CSTNode) Replace(node Node) {
+	n.Node = node
+	n.Kind = ""
+	n.Text = node.String()
+	n.Children = nil
+}
+
+// Tokens returns the tokens in the source order.
+func (n *CSTNode) Tokens() []*CSTNode {
+	var tokens []*CSTNode
+
+	n.Inspect(func(node *CSTNode) bool {
+		if node.Kind != "" {
+			tokens = append(tokens, node)
+		}
+
+		return true
+	})
+
+	return tokens
+}
+
+// Inspect traverses the node and its children in depth-first order. If f returns false,
+// the children of the node are not traversed.
+func (n *CSTNode) Inspect(f func(*CSTNode) bool) {
+	if !f(n) {
+		return
+	}
+
+	for _, child := range n.Children {
+		child.Inspect(f)
+	}
+}
+
+// ParseCST parses the input string and returns the lossless concrete syntax tree.
+func ParseCST(input string) (*CST, error) {
+	p := newParser(input)
+
+	message, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("parse CST: %w", err)
+	}
+
+	b := cstBuilder{tokens: p.tokens()}
+	root := b.build(message, p.span(0, len(input)))
+
+	return &CST{Root: root}, nil
+}
+
+// tokens returns the tokens of the lexed items. The skipped input, i.e. the bidi marks
+// without whitespace, is returned as whitespace.
+func (p *parser) tokens() []*CSTNode {
+	var (
+		tokens []*CSTNode
+		offset int
+	)
+
+	whitespace := func(end int) {
+		if offset < end {
+			tokens = append(tokens, &CSTNode{Kind: TokenWhitespace, Text: p.input[offset:end], Span: p.span(offset, end)})
+		}
+	}
+
+	for _, itm := range p.items {
+		if itm.typ == itemEOF {
+			break
+		}
+
+		whitespace(itm.pos)
+
+		token := &CSTNode{
+			Kind: TokenKind(itm.typ.String()),
+			Text: p.input[itm.pos:itm.end],
+			Span: p.span(itm.pos, itm.end),
+		}
+
+		switch itm.typ { //nolint:exhaustive
+		case itemVariable:
+			token.Node = Variable(itm.val)
+		case itemQuotedLiteral:
+			token.Node = QuotedLiteral(itm.val)
+		case itemUnquotedLiteral:
+			token.Node = NameLiteral(itm.val)
+		case itemText:
+			token.Node = Text(itm.val)
+		}
+
+		tokens = append(tokens, token)
+		offset = itm.end
+	}
+
+	whitespace(len(p.input))
+
+	return tokens
+}
+
+// cstBuilder groups the tokens into the subtrees by the spans of the AST nodes.
+type cstBuilder struct {
+	tokens []*CSTNode
+	pos    int // current token
+}
+
+func (b *cstBuilder) build(node Node, span Span) *CSTNode {
+	subtree := &CSTNode{Node: node, Span: span, Children: []*CSTNode{}}
+	children := cstChildren(node)
+
+	for b.pos < len(b.tokens) {
+		token := b.tokens[b.pos]
+		if token.Span.Start.Offset >= span.End.Offset {
+			break
+		}
+
+		// the child node not aligned with the tokens is a part of the token
+		for len(children) > 0 && nodeSpan(children[0]).Start.Offset < token.Span.Start.Offset {
+			children = children[1:]
+		}
+
+		if len(children) > 0 && nodeSpan(children[0]).Start.Offset == token.Span.Start.Offset {
+			subtree.Children = append(subtree.Children, b.build(children[0], nodeSpan(children[0])))
+			children = children[1:]
+
+			continue
+		}
+
+		subtree.Children = append(subtree.Children, token)
+		b.pos++
+	}
+
+	return subtree
+}
+
+// cstChildren returns the closest descendants of the node that are the subtrees.
+func cstChildren(node Node) []Node {
+	var children []Node
+
+	if node == nil {
+		return nil
+	}
+
+	root := true
+
+	Inspect(node, func(n Node) bool {
+		switch n.(type) {
+		case nil, Identifier, CatchAllKey:
+			return false
+		}
+
+		// the nodes without span, e.g. the quoted pattern, are not the subtrees
+		if root || nodeSpan(n).IsZero() {
+			root = false
+			return true
+		}
+
+		children = append(children, n)
+
+		return false
+	})
+
+	return children
+}
+
+// nodeSpan returns the span of the node, zero for the nodes without span.
+func nodeSpan(node Node) Span {
+	switch n := node.(type) {
+	default:
+		return Span{}
+	case ComplexMessage:
+		return n.Span
+	case InputDeclaration:
+		return n.Span
+	case LocalDeclaration:
+		return n.Span
+	case Expression:
+		return n.Span
+	case Function:
+		return n.Span
+	case Option:
+		return n.Span
+	case Attribute:
+		return n.Span
+	case Markup:
+		return n.Span
+	case Matcher:
+		return n.Span
+	case Variant:
+		return n.Span
+	}
+}
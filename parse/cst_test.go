@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseCST(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"",
+		"  Hello \\{ {$x   :number  minimumFractionDigits=2 @a=b}|x {#b u:id=x/}{/b}!",
+		".input {$n :number}\n.local $x = {|a\\|b| :string}\n.match $n\none {{one {$x}}}\n* {{other}}\n",
+		"{{ quoted }}  ",
+		"‎{؜$x‎}",
+		".local $x = {1}   .local $y = {$x :number} {{{$y} {#i/}}}",
+	} {
+		cst, err := ParseCST(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if input != cst.String() {
+			t.Errorf("want '%s', got '%s'", input, cst)
+		}
+
+		var sb strings.Builder
+
+		for _, token := range cst.Root.Tokens() {
+			sb.WriteString(token.Text)
+		}
+
+		if input != sb.String() {
+			t.Errorf("want tokens '%s', got '%s'", input, sb.String())
+		}
+	}
+}
+
+func TestParseCSTSubtrees(t *testing.T) {
+	t.Parallel()
+
+	cst, err := ParseCST(".local $x = {$y :number minimumFractionDigits=2}\n.match $x\none {{{#b}one{/b}}}\n*   {{other}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	cst.Root.Inspect(func(node *CSTNode) bool {
+		if node.Kind == "" {
+			got = append(got, strings.TrimPrefix(fmt.Sprintf("%T", node.Node), "parse.")+" "+node.String())
+		}
+
+		return true
+	})
+
+	want := []string{
+		"ComplexMessage .local $x = {$y :number minimumFractionDigits=2}\n.match $x\none {{{#b}one{/b}}}\n*   {{other}}",
+		"LocalDeclaration .local $x = {$y :number minimumFractionDigits=2}",
+		"Expression {$y :number minimumFractionDigits=2}",
+		"Function :number minimumFractionDigits=2",
+		"Option minimumFractionDigits=2",
+		"Matcher .match $x\none {{{#b}one{/b}}}\n*   {{other}}",
+		"Variant one {{{#b}one{/b}}}",
+		"Markup {#b}",
+		"Markup {/b}",
+		"Variant *   {{other}}",
+	}
+
+	if strings.Join(want, "\n---\n") != strings.Join(got, "\n---\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n---\n"), strings.Join(got, "\n---\n"))
+	}
+}
+
+func TestCSTEdit(t *testing.T) {
+	t.Parallel()
+
+	input := ".input {$count   :number}\n{{You have {  $count  } item\\{s\\} {|x|}}}"
+
+	cst, err := ParseCST(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rename variable
+	for _, token := range cst.Root.Tokens() {
+		if token.Kind == TokenVariable && token.Node == Variable("count") {
+			token.Text = "$total"
+		}
+	}
+
+	// replace expression
+	cst.Root.Inspect(func(node *CSTNode) bool {
+		if expr, ok := node.Node.(Expression); ok && expr.Operand == QuotedLiteral("x") {
+			node.Replace(Expression{Operand: NameLiteral("y")})
+			return false
+		}
+
+		return true
+	})
+
+	want := ".input {$total   :number}\n{{You have {  $total  } item\\{s\\} { y }}}"
+	if want != cst.String() {
+		t.Errorf("want '%s', got '%s'", want, cst)
+	}
+
+	ast, err := cst.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := ".input { $total :number }\n{{You have { $total } item\\{s\\} { y }}}"; want != ast.String() {
+		t.Errorf("want AST '%s', got '%s'", want, ast)
+	}
+}
+
+func TestParseCSTError(t *testing.T) {
+	t.Parallel()
+
+	want := "parse CST: syntax error at 1:9: unexpected eof in expression"
+
+	if _, err := ParseCST("Hello, {"); err == nil || err.Error() != want {
+		t.Errorf("want error '%s', got '%v'", want, err)
+	}
+}
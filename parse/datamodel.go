@@ -0,0 +1,930 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The types in the JSON data model, see ".message-format-wg/spec/data-model/message.json".
+const (
+	dataModelMessage     = "message"
+	dataModelSelect      = "select"
+	dataModelInput       = "input"
+	dataModelLocal       = "local"
+	dataModelExpression  = "expression"
+	dataModelMarkup      = "markup"
+	dataModelFunction    = "function"
+	dataModelLiteral     = "literal"
+	dataModelVariable    = "variable"
+	dataModelCatchAllKey = "*"
+)
+
+/*
+MarshalDataModel returns the JSON encoding of the message in the MF2 data model
+defined by the specification, see ".message-format-wg/spec/data-model/message.json".
+
+The data model is the interchange format between MF2 implementations. The spans are not encoded,
+the options and attributes are encoded in the source order.
+
+Example:
+
+	tree, _ := parse.Parse("Hello, { $name }!")
+	data, _ := parse.MarshalDataModel(tree.Message)
+
+	fmt.Println(string(data))
+	// {"type":"message","declarations":[],"pattern":["Hello, ",{"type":"expression","arg":{"type":"variable","name":"name"}},"!"]}
+*/
+func MarshalDataModel(message Message) ([]byte, error) {
+	v, err := encodeMessage(message)
+	if err != nil {
+		return nil, fmt.Errorf("marshal data model: %w", err)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal data model: %w", err)
+	}
+
+	return data, nil
+}
+
+/*
+UnmarshalDataModel decodes the message from the JSON encoding of the MF2 data model,
+see [MarshalDataModel].
+
+The structure and the names in the message are validated. The data model errors,
+e.g. duplicate declarations, are reported when the message is parsed or executed.
+
+Example:
+
+	message, _ := parse.UnmarshalDataModel(data)
+
+	fmt.Println(message) // Hello, { $name }!
+*/
+func UnmarshalDataModel(data []byte) (Message, error) {
+	var node dataModelNode
+
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("unmarshal data model: %w", err)
+	}
+
+	message, err := decodeMessage(node)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal data model: %w", err)
+	}
+
+	return message, nil
+}
+
+// ---------------------------------Encoding---------------------------------
+
+type patternMessageModel struct {
+	Type         string `json:"type"`
+	Declarations []any  `json:"declarations"`
+	Pattern      []any  `json:"pattern"`
+}
+
+type selectMessageModel struct {
+	Type         string          `json:"type"`
+	Declarations []any           `json:"declarations"`
+	Selectors    []variableModel `json:"selectors"`
+	Variants     []variantModel  `json:"variants"`
+}
+
+type declarationModel struct {
+	Type  string          `json:"type"`
+	Name  string          `json:"name"`
+	Value expressionModel `json:"value"`
+}
+
+type variantModel struct {
+	Keys  []any `json:"keys"`
+	Value []any `json:"value"`
+}
+
+type expressionModel struct {
+	Type       string          `json:"type"`
+	Arg        any             `json:"arg,omitempty"`
+	Function   *functionModel  `json:"function,omitempty"`
+	Attributes dataModelFields `json:"attributes,omitempty"`
+}
+
+type functionModel struct {
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Options dataModelFields `json:"options,omitempty"`
+}
+
+type markupModel struct {
+	Type       string          `json:"type"`
+	Kind       string          `json:"kind"`
+	Name       string          `json:"name"`
+	Options    dataModelFields `json:"options,omitempty"`
+	Attributes dataModelFields `json:"attributes,omitempty"`
+}
+
+type literalModel struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type variableModel struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type catchAllKeyModel struct {
+	Type string `json:"type"`
+}
+
+// dataModelField is a named value of the options or attributes.
+type dataModelField struct {
+	Value any
+	Name  string
+}
+
+// dataModelFields are the options or attributes encoded as JSON object in the source order.
+type dataModelFields []dataModelField
+
+func (f dataModelFields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, fmt.Errorf("name: %w", err)
+		}
+
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("value of %s: %w", field.Name, err)
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes the JSON object preserving the order of the fields.
+// The field values are [json.RawMessage].
+func (f *dataModelFields) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("want object, got %s", data)
+	}
+
+	*f = (*f)[:0]
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("field name: %w", err)
+		}
+
+		name, _ := tok.(string) // object keys are always strings
+
+		var value json.RawMessage
+
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+
+		*f = append(*f, dataModelField{Name: name, Value: value})
+	}
+
+	return nil
+}
+
+func encodeMessage(message Message) (any, error) {
+	switch m := message.(type) {
+	default:
+		return nil, fmt.Errorf("unsupported message %T", message)
+	case nil: // empty message, see [Parse]
+		return patternMessageModel{Type: dataModelMessage, Declarations: []any{}, Pattern: []any{}}, nil
+	case SimpleMessage:
+		pattern, err := encodePattern(m)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+
+		return patternMessageModel{Type: dataModelMessage, Declarations: []any{}, Pattern: pattern}, nil
+	case ComplexMessage:
+		return encodeComplexMessage(m)
+	}
+}
+
+func encodeComplexMessage(message ComplexMessage) (any, error) {
+	declarations := make([]any, 0, len(message.Declarations))
+
+	for i, declaration := range message.Declarations {
+		v, err := encodeDeclaration(declaration)
+		if err != nil {
+			return nil, fmt.Errorf("declaration %d: %w", i, err)
+		}
+
+		declarations = append(declarations, v)
+	}
+
+	switch body := message.ComplexBody.(type) {
+	default:
+		return nil, fmt.Errorf("unsupported complex body %T", message.ComplexBody)
+	case QuotedPattern:
+		pattern, err := encodePattern(body)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+
+		return patternMessageModel{Type: dataModelMessage, Declarations: declarations, Pattern: pattern}, nil
+	case Matcher:
+		selectors := make([]variableModel, 0, len(body.Selectors))
+
+		for _, selector := range body.Selectors {
+			selectors = append(selectors, variableModel{Type: dataModelVariable, Name: string(selector)})
+		}
+
+		variants := make([]variantModel, 0, len(body.Variants))
+
+		for i, variant := range body.Variants {
+			v, err := encodeVariant(variant)
+			if err != nil {
+				return nil, fmt.Errorf("variant %d: %w", i, err)
+			}
+
+			variants = append(variants, v)
+		}
+
+		return selectMessageModel{
+			Type:         dataModelSelect,
+			Declarations: declarations,
+			Selectors:    selectors,
+			Variants:     variants,
+		}, nil
+	}
+}
+
+func encodeDeclaration(declaration Declaration) (any, error) {
+	switch d := declaration.(type) {
+	default:
+		return nil, fmt.Errorf("unsupported declaration %T", declaration)
+	case InputDeclaration:
+		variable, ok := d.Operand.(Variable)
+		if !ok {
+			return nil, fmt.Errorf("want variable operand in input declaration, got %T", d.Operand)
+		}
+
+		expr, err := encodeExpression(Expression(d))
+		if err != nil {
+			return nil, err
+		}
+
+		return declarationModel{Type: dataModelInput, Name: string(variable), Value: expr}, nil
+	case LocalDeclaration:
+		expr, err := encodeExpression(d.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return declarationModel{Type: dataModelLocal, Name: string(d.Variable), Value: expr}, nil
+	}
+}
+
+func encodeVariant(variant Variant) (variantModel, error) {
+	keys := make([]any, 0, len(variant.Keys))
+
+	for _, key := range variant.Keys {
+		switch k := key.(type) {
+		default:
+			return variantModel{}, fmt.Errorf("unsupported variant key %T", key)
+		case CatchAllKey:
+			keys = append(keys, catchAllKeyModel{Type: dataModelCatchAllKey})
+		case Literal:
+			keys = append(keys, encodeLiteral(k))
+		}
+	}
+
+	pattern, err := encodePattern(variant.QuotedPattern)
+	if err != nil {
+		return variantModel{}, fmt.Errorf("pattern: %w", err)
+	}
+
+	return variantModel{Keys: keys, Value: pattern}, nil
+}
+
+func encodePattern(pattern []PatternPart) ([]any, error) {
+	parts := make([]any, 0, len(pattern))
+
+	for i, part := range pattern {
+		switch p := part.(type) {
+		default:
+			return nil, fmt.Errorf("unsupported pattern part %T", part)
+		case Text:
+			parts = append(parts, string(p))
+		case Expression:
+			expr, err := encodeExpression(p)
+			if err != nil {
+				return nil, fmt.Errorf("part %d: %w", i, err)
+			}
+
+			parts = append(parts, expr)
+		case Markup:
+			markup, err := encodeMarkup(p)
+			if err != nil {
+				return nil, fmt.Errorf("part %d: %w", i, err)
+			}
+
+			parts = append(parts, markup)
+		}
+	}
+
+	return parts, nil
+}
+
+func encodeExpression(expr Expression) (expressionModel, error) {
+	model := expressionModel{Type: dataModelExpression}
+
+	if expr.Operand != nil {
+		model.Arg = encodeValue(expr.Operand)
+	}
+
+	switch annotation := expr.Annotation.(type) {
+	default:
+		return expressionModel{}, fmt.Errorf("unsupported annotation %T", expr.Annotation)
+	case nil:
+		if expr.Operand == nil {
+			return expressionModel{}, errors.New("expression without operand and function")
+		}
+	case Function:
+		model.Function = &functionModel{
+			Type:    dataModelFunction,
+			Name:    annotation.Identifier.String(),
+			Options: encodeOptions(annotation.Options),
+		}
+	}
+
+	attributes, err := encodeAttributes(expr.Attributes)
+	if err != nil {
+		return expressionModel{}, err
+	}
+
+	model.Attributes = attributes
+
+	return model, nil
+}
+
+func encodeMarkup(markup Markup) (markupModel, error) {
+	model := markupModel{
+		Type:    dataModelMarkup,
+		Name:    markup.Identifier.String(),
+		Options: encodeOptions(markup.Options),
+	}
+
+	switch markup.Typ {
+	default:
+		return markupModel{}, fmt.Errorf("unsupported markup type %d", markup.Typ)
+	case Open:
+		model.Kind = "open"
+	case Close:
+		model.Kind = "close"
+	case SelfClose:
+		model.Kind = "standalone"
+	}
+
+	attributes, err := encodeAttributes(markup.Attributes)
+	if err != nil {
+		return markupModel{}, err
+	}
+
+	model.Attributes = attributes
+
+	return model, nil
+}
+
+func encodeOptions(options []Option) dataModelFields {
+	fields := make(dataModelFields, 0, len(options))
+
+	for _, option := range options {
+		fields = append(fields, dataModelField{Name: option.Identifier.String(), Value: encodeValue(option.Value)})
+	}
+
+	return fields
+}
+
+// encodeAttributes encodes the attributes, the attribute without value is encoded as true.
+func encodeAttributes(attributes []Attribute) (dataModelFields, error) {
+	fields := make(dataModelFields, 0, len(attributes))
+
+	for _, attribute := range attributes {
+		field := dataModelField{Name: attribute.Identifier.String(), Value: true}
+
+		switch v := attribute.Value.(type) {
+		default:
+			return nil, fmt.Errorf("attribute %s: want literal value, got %T", field.Name, attribute.Value)
+		case nil:
+		case Literal:
+			field.Value = encodeLiteral(v)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func encodeValue(value Value) any {
+	if variable, ok := value.(Variable); ok {
+		return variableModel{Type: dataModelVariable, Name: string(variable)}
+	}
+
+	return literalModel{Type: dataModelLiteral, Value: literalValue(value)}
+}
+
+func encodeLiteral(literal Literal) literalModel {
+	return literalModel{Type: dataModelLiteral, Value: literalValue(literal)}
+}
+
+func literalValue(value Value) string {
+	switch v := value.(type) {
+	default:
+		return value.String()
+	case QuotedLiteral:
+		return string(v)
+	case NameLiteral:
+		return string(v)
+	}
+}
+
+// ---------------------------------Decoding---------------------------------
+
+// dataModelNode is any object in the JSON data model.
+type dataModelNode struct {
+	Arg          *dataModelNode    `json:"arg"`
+	Function     *dataModelNode    `json:"function"`
+	Type         string            `json:"type"`
+	Kind         string            `json:"kind"`
+	Name         string            `json:"name"`
+	Value        json.RawMessage   `json:"value"` // literal value, declaration expression or variant pattern
+	Options      dataModelFields   `json:"options"`
+	Attributes   dataModelFields   `json:"attributes"`
+	Declarations []dataModelNode   `json:"declarations"`
+	Pattern      []json.RawMessage `json:"pattern"`
+	Selectors    []dataModelNode   `json:"selectors"`
+	Variants     []dataModelNode   `json:"variants"`
+	Keys         []dataModelNode   `json:"keys"`
+}
+
+func decodeMessage(node dataModelNode) (Message, error) {
+	declarations := make([]Declaration, 0, len(node.Declarations))
+
+	for i, declaration := range node.Declarations {
+		d, err := decodeDeclaration(declaration)
+		if err != nil {
+			return nil, fmt.Errorf("declaration %d: %w", i, err)
+		}
+
+		declarations = append(declarations, d)
+	}
+
+	switch node.Type {
+	default:
+		return nil, unexpectedTypeErr(node.Type, dataModelMessage, dataModelSelect)
+	case dataModelMessage:
+		if node.Pattern == nil {
+			return nil, errors.New("missing pattern")
+		}
+
+		pattern, err := decodePattern(node.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+
+		if len(declarations) == 0 && isSimplePattern(pattern) {
+			return SimpleMessage(pattern), nil
+		}
+
+		return ComplexMessage{Declarations: declarations, ComplexBody: QuotedPattern(pattern)}, nil
+	case dataModelSelect:
+		matcher, err := decodeMatcher(node)
+		if err != nil {
+			return nil, err
+		}
+
+		return ComplexMessage{Declarations: declarations, ComplexBody: matcher}, nil
+	}
+}
+
+// isSimplePattern returns true if the pattern can be written as a simple message,
+// i.e. it does not start with a keyword.
+func isSimplePattern(pattern []PatternPart) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+
+	text, ok := pattern[0].(Text)
+
+	return !ok || !strings.HasPrefix(strings.TrimLeftFunc(string(text), isWhitespace), ".")
+}
+
+func decodeMatcher(node dataModelNode) (Matcher, error) {
+	if len(node.Selectors) == 0 {
+		return Matcher{}, errors.New("missing selectors")
+	}
+
+	if len(node.Variants) == 0 {
+		return Matcher{}, errors.New("missing variants")
+	}
+
+	matcher := Matcher{
+		Selectors: make([]Variable, 0, len(node.Selectors)),
+		Variants:  make([]Variant, 0, len(node.Variants)),
+	}
+
+	for i, selector := range node.Selectors {
+		variable, err := decodeVariable(selector)
+		if err != nil {
+			return Matcher{}, fmt.Errorf("selector %d: %w", i, err)
+		}
+
+		matcher.Selectors = append(matcher.Selectors, variable)
+	}
+
+	for i, variant := range node.Variants {
+		v, err := decodeVariant(variant)
+		if err != nil {
+			return Matcher{}, fmt.Errorf("variant %d: %w", i, err)
+		}
+
+		matcher.Variants = append(matcher.Variants, v)
+	}
+
+	return matcher, nil
+}
+
+func decodeDeclaration(node dataModelNode) (Declaration, error) {
+	if node.Type != dataModelInput && node.Type != dataModelLocal {
+		return nil, unexpectedTypeErr(node.Type, dataModelInput, dataModelLocal)
+	}
+
+	variable, err := decodeName(node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var value dataModelNode
+
+	if err = decodeRaw(node.Value, &value); err != nil {
+		return nil, fmt.Errorf("value: %w", err)
+	}
+
+	expr, err := decodeExpression(value)
+	if err != nil {
+		return nil, fmt.Errorf("value: %w", err)
+	}
+
+	if node.Type == dataModelLocal {
+		return LocalDeclaration{Variable: Variable(variable), Expression: expr}, nil
+	}
+
+	if operand, ok := expr.Operand.(Variable); !ok || string(operand) != variable {
+		return nil, fmt.Errorf(`want input declaration value with variable "%s", got "%v"`, variable, expr.Operand)
+	}
+
+	return InputDeclaration(expr), nil
+}
+
+func decodeVariant(node dataModelNode) (Variant, error) {
+	if len(node.Keys) == 0 {
+		return Variant{}, errors.New("missing keys")
+	}
+
+	variant := Variant{Keys: make([]VariantKey, 0, len(node.Keys))}
+
+	for i, key := range node.Keys {
+		switch key.Type {
+		default:
+			return Variant{}, fmt.Errorf("key %d: %w", i, unexpectedTypeErr(key.Type, dataModelLiteral, dataModelCatchAllKey))
+		case dataModelCatchAllKey:
+			variant.Keys = append(variant.Keys, CatchAllKey{})
+		case dataModelLiteral:
+			literal, err := decodeLiteral(key)
+			if err != nil {
+				return Variant{}, fmt.Errorf("key %d: %w", i, err)
+			}
+
+			variant.Keys = append(variant.Keys, literal)
+		}
+	}
+
+	var value []json.RawMessage
+
+	if err := decodeRaw(node.Value, &value); err != nil {
+		return Variant{}, fmt.Errorf("value: %w", err)
+	}
+
+	pattern, err := decodePattern(value)
+	if err != nil {
+		return Variant{}, fmt.Errorf("value: %w", err)
+	}
+
+	variant.QuotedPattern = pattern
+
+	return variant, nil
+}
+
+func decodePattern(parts []json.RawMessage) ([]PatternPart, error) {
+	pattern := make([]PatternPart, 0, len(parts))
+
+	for i, raw := range parts {
+		part, err := decodePatternPart(raw)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+
+		pattern = append(pattern, part)
+	}
+
+	return pattern, nil
+}
+
+func decodePatternPart(raw json.RawMessage) (PatternPart, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte(`"`)) {
+		var text string
+
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, fmt.Errorf("text: %w", err)
+		}
+
+		return Text(text), nil
+	}
+
+	var node dataModelNode
+
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	switch node.Type {
+	default:
+		return nil, unexpectedTypeErr(node.Type, dataModelExpression, dataModelMarkup)
+	case dataModelExpression:
+		return decodeExpression(node)
+	case dataModelMarkup:
+		return decodeMarkup(node)
+	}
+}
+
+func decodeExpression(node dataModelNode) (Expression, error) {
+	if node.Type != dataModelExpression {
+		return Expression{}, unexpectedTypeErr(node.Type, dataModelExpression)
+	}
+
+	if node.Arg == nil && node.Function == nil {
+		return Expression{}, errors.New("expression without arg and function")
+	}
+
+	var (
+		expr Expression
+		err  error
+	)
+
+	if node.Arg != nil {
+		if expr.Operand, err = decodeValue(*node.Arg); err != nil {
+			return Expression{}, fmt.Errorf("arg: %w", err)
+		}
+	}
+
+	if node.Function != nil {
+		if node.Function.Type != dataModelFunction {
+			return Expression{}, fmt.Errorf("function: %w", unexpectedTypeErr(node.Function.Type, dataModelFunction))
+		}
+
+		function := Function{}
+
+		if function.Identifier, err = decodeIdentifier(node.Function.Name); err != nil {
+			return Expression{}, fmt.Errorf("function: %w", err)
+		}
+
+		if function.Options, err = decodeOptions(node.Function.Options); err != nil {
+			return Expression{}, fmt.Errorf("function %s: %w", function.Identifier, err)
+		}
+
+		expr.Annotation = function
+	}
+
+	if expr.Attributes, err = decodeAttributes(node.Attributes); err != nil {
+		return Expression{}, err
+	}
+
+	return expr, nil
+}
+
+func decodeMarkup(node dataModelNode) (Markup, error) {
+	var (
+		markup Markup
+		err    error
+	)
+
+	switch node.Kind {
+	default:
+		return Markup{}, fmt.Errorf(`want markup kind "open", "standalone" or "close", got "%s"`, node.Kind)
+	case "open":
+		markup.Typ = Open
+	case "close":
+		markup.Typ = Close
+	case "standalone":
+		markup.Typ = SelfClose
+	}
+
+	if markup.Identifier, err = decodeIdentifier(node.Name); err != nil {
+		return Markup{}, fmt.Errorf("markup: %w", err)
+	}
+
+	if markup.Options, err = decodeOptions(node.Options); err != nil {
+		return Markup{}, fmt.Errorf("markup %s: %w", markup.Identifier, err)
+	}
+
+	if markup.Attributes, err = decodeAttributes(node.Attributes); err != nil {
+		return Markup{}, fmt.Errorf("markup %s: %w", markup.Identifier, err)
+	}
+
+	return markup, nil
+}
+
+func decodeOptions(fields dataModelFields) ([]Option, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	options := make([]Option, 0, len(fields))
+
+	for _, field := range fields {
+		identifier, err := decodeIdentifier(field.Name)
+		if err != nil {
+			return nil, fmt.Errorf("option: %w", err)
+		}
+
+		var node dataModelNode
+
+		if err = decodeRaw(field.Value, &node); err != nil {
+			return nil, fmt.Errorf("option %s: %w", field.Name, err)
+		}
+
+		value, err := decodeValue(node)
+		if err != nil {
+			return nil, fmt.Errorf("option %s: %w", field.Name, err)
+		}
+
+		options = append(options, Option{Identifier: identifier, Value: value})
+	}
+
+	return options, nil
+}
+
+// decodeAttributes decodes the attributes, the attribute with the value true has no value.
+func decodeAttributes(fields dataModelFields) ([]Attribute, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	attributes := make([]Attribute, 0, len(fields))
+
+	for _, field := range fields {
+		identifier, err := decodeIdentifier(field.Name)
+		if err != nil {
+			return nil, fmt.Errorf("attribute: %w", err)
+		}
+
+		attribute := Attribute{Identifier: identifier}
+
+		if raw, _ := field.Value.(json.RawMessage); string(bytes.TrimSpace(raw)) != "true" {
+			var node dataModelNode
+
+			if err = decodeRaw(raw, &node); err != nil {
+				return nil, fmt.Errorf("attribute %s: %w", field.Name, err)
+			}
+
+			if attribute.Value, err = decodeLiteral(node); err != nil {
+				return nil, fmt.Errorf("attribute %s: %w", field.Name, err)
+			}
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+func decodeValue(node dataModelNode) (Value, error) {
+	switch node.Type {
+	default:
+		return nil, unexpectedTypeErr(node.Type, dataModelLiteral, dataModelVariable)
+	case dataModelLiteral:
+		return decodeLiteral(node)
+	case dataModelVariable:
+		return decodeVariable(node)
+	}
+}
+
+func decodeVariable(node dataModelNode) (Variable, error) {
+	if node.Type != dataModelVariable {
+		return "", unexpectedTypeErr(node.Type, dataModelVariable)
+	}
+
+	name, err := decodeName(node.Name)
+	if err != nil {
+		return "", err
+	}
+
+	return Variable(name), nil
+}
+
+// decodeLiteral decodes the literal, the literal is unquoted if possible.
+func decodeLiteral(node dataModelNode) (Literal, error) {
+	if node.Type != dataModelLiteral {
+		return nil, unexpectedTypeErr(node.Type, dataModelLiteral)
+	}
+
+	var value string
+
+	if err := decodeRaw(node.Value, &value); err != nil {
+		return nil, fmt.Errorf("literal value: %w", err)
+	}
+
+	if isUnquotedLiteral(value) {
+		return NameLiteral(value), nil
+	}
+
+	return QuotedLiteral(value), nil
+}
+
+// decodeIdentifier decodes the identifier with optional namespace, e.g. "ns:name".
+func decodeIdentifier(s string) (Identifier, error) {
+	namespace, name, ok := strings.Cut(s, ":")
+	if !ok {
+		name, namespace = namespace, ""
+	}
+
+	if ok && !isValidName(namespace) || !isValidName(name) {
+		return Identifier{}, fmt.Errorf(`bad identifier "%s"`, s)
+	}
+
+	return Identifier{Namespace: namespace, Name: name}, nil
+}
+
+func decodeName(s string) (string, error) {
+	if !isValidName(s) {
+		return "", fmt.Errorf(`bad name "%s"`, s)
+	}
+
+	return s, nil
+}
+
+// isValidName returns true if s is a valid name without the bidi marks.
+//
+// ABNF:
+//
+//	name = [bidi] name-start *name-char [bidi]
+func isValidName(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+
+	return isNameStart(r) && strings.IndexFunc(s, func(r rune) bool { return !isName(r) }) == -1
+}
+
+// decodeRaw decodes the required value.
+func decodeRaw(raw any, v any) error {
+	data, _ := raw.(json.RawMessage)
+	if len(data) == 0 {
+		return errors.New("missing value")
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return nil
+}
+
+func unexpectedTypeErr(actual string, expected ...string) error {
+	quoted := make([]string, len(expected))
+
+	for i, v := range expected {
+		quoted[i] = `"` + v + `"`
+	}
+
+	return fmt.Errorf(`want type %s, got "%s"`, joinOr(quoted), actual)
+}
@@ -0,0 +1,203 @@
+package parse
+
+import "testing"
+
+func TestMarshalDataModel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "empty message",
+			input: "",
+			want:  `{"type":"message","declarations":[],"pattern":[]}`,
+		},
+		{
+			name:  "simple message",
+			input: "Hello, { $name }!",
+			want: `{"type":"message","declarations":[],"pattern":["Hello, ",` +
+				`{"type":"expression","arg":{"type":"variable","name":"name"}},"!"]}`,
+		},
+		{
+			name:  "declarations",
+			input: ".input {$n :number minimumFractionDigits=2} .local $x = {|a b| @translate=no @track} {{{$x}}}",
+			want: `{"type":"message","declarations":[` +
+				`{"type":"input","name":"n","value":{"type":"expression","arg":{"type":"variable","name":"n"},` +
+				`"function":{"type":"function","name":"number","options":{"minimumFractionDigits":{"type":"literal","value":"2"}}}}},` +
+				`{"type":"local","name":"x","value":{"type":"expression","arg":{"type":"literal","value":"a b"},` +
+				`"attributes":{"translate":{"type":"literal","value":"no"},"track":true}}}],` +
+				`"pattern":[{"type":"expression","arg":{"type":"variable","name":"x"}}]}`,
+		},
+		{
+			name:  "select",
+			input: ".input {$n :number} .match $n one {{one}} * {{other}}",
+			want: `{"type":"select","declarations":[` +
+				`{"type":"input","name":"n","value":{"type":"expression","arg":{"type":"variable","name":"n"},` +
+				`"function":{"type":"function","name":"number"}}}],` +
+				`"selectors":[{"type":"variable","name":"n"}],` +
+				`"variants":[{"keys":[{"type":"literal","value":"one"}],"value":["one"]},` +
+				`{"keys":[{"type":"*"}],"value":["other"]}]}`,
+		},
+		{
+			name:  "markup",
+			input: "{#a href=$url u:id=x @a:b}link{/a}{#br/}",
+			want: `{"type":"message","declarations":[],"pattern":[` +
+				`{"type":"markup","kind":"open","name":"a","options":{"href":{"type":"variable","name":"url"},` +
+				`"u:id":{"type":"literal","value":"x"}},"attributes":{"a:b":true}},"link",` +
+				`{"type":"markup","kind":"close","name":"a"},{"type":"markup","kind":"standalone","name":"br"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := MarshalDataModel(tree.Message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != string(data) {
+				t.Errorf("want\n%s\ngot\n%s", test.want, data)
+			}
+
+			message, err := UnmarshalDataModel(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tree.String() != message.String() {
+				t.Errorf("want '%s', got '%s'", tree, message)
+			}
+
+			data, err = MarshalDataModel(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != string(data) {
+				t.Errorf("want round-trip\n%s\ngot\n%s", test.want, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDataModel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "quoted literal",
+			input: `{"type":"message","declarations":[],"pattern":[{"type":"expression","arg":{"type":"literal","value":"a|b"}}]}`,
+			want:  `{ |a\|b| }`,
+		},
+		{
+			name:  "empty pattern",
+			input: `{"type":"message","declarations":[],"pattern":[]}`,
+			want:  ``,
+		},
+		{
+			name:  "pattern starting with keyword",
+			input: `{"type":"message","declarations":[],"pattern":[" .local"]}`,
+			want:  `{{ .local}}`,
+		},
+		{
+			name: "function without operand",
+			input: `{"type":"message","declarations":[],"pattern":[` +
+				`{"type":"expression","function":{"type":"function","name":"ns:f"},"attributes":{"x":true}}]}`,
+			want: `{ :ns:f @x }`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			message, err := UnmarshalDataModel([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != message.String() {
+				t.Errorf("want '%s', got '%s'", test.want, message)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDataModelErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "unknown message type",
+			input: `{"type":"x"}`,
+			want:  `unmarshal data model: want type "message" or "select", got "x"`,
+		},
+		{
+			name:  "missing pattern",
+			input: `{"type":"message","declarations":[]}`,
+			want:  `unmarshal data model: missing pattern`,
+		},
+		{
+			name:  "bad variable name",
+			input: `{"type":"message","declarations":[],"pattern":[{"type":"expression","arg":{"type":"variable","name":"1x"}}]}`,
+			want:  `unmarshal data model: pattern: part 0: arg: bad name "1x"`,
+		},
+		{
+			name:  "expression without arg and function",
+			input: `{"type":"message","declarations":[],"pattern":[{"type":"expression"}]}`,
+			want:  `unmarshal data model: pattern: part 0: expression without arg and function`,
+		},
+		{
+			name: "input declaration with literal",
+			input: `{"type":"message","declarations":[{"type":"input","name":"x",` +
+				`"value":{"type":"expression","arg":{"type":"literal","value":"x"}}}],"pattern":[]}`,
+			want: `unmarshal data model: declaration 0: want input declaration value with variable "x", got "x"`,
+		},
+		{
+			name:  "missing variants",
+			input: `{"type":"select","declarations":[],"selectors":[{"type":"variable","name":"x"}],"variants":[]}`,
+			want:  `unmarshal data model: missing variants`,
+		},
+		{
+			name: "bad markup kind",
+			input: `{"type":"message","declarations":[],"pattern":[` +
+				`{"type":"markup","kind":"x","name":"b"}]}`,
+			want: `unmarshal data model: pattern: part 0: want markup kind "open", "standalone" or "close", got "x"`,
+		},
+		{
+			name: "variable attribute",
+			input: `{"type":"message","declarations":[],"pattern":[` +
+				`{"type":"expression","arg":{"type":"literal","value":"x"},"attributes":{"a":{"type":"variable","name":"y"}}}]}`,
+			want: `unmarshal data model: pattern: part 0: attribute a: want type "literal", got "variable"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := UnmarshalDataModel([]byte(test.input))
+			if err == nil || err.Error() != test.want {
+				t.Errorf("want error '%s', got '%v'", test.want, err)
+			}
+		})
+	}
+}
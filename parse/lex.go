@@ -101,6 +101,8 @@ type item struct {
 	err error
 	val string
 	typ itemType
+	pos int // byte offset of the item in the input, including the skipped sigil, e.g. "$" or ":"
+	end int // byte offset after the item in the input
 }
 
 func (i item) String() string {
@@ -139,6 +141,7 @@ type lexer struct {
 	prevType   itemType // previous non-whitespace item type
 	start, end int      // start and end positions of the item to be emitted
 	line       int      // line number
+	sigil      int      // length of the sigil skipped from the value of the item to be emitted
 
 	isFunction,
 	isMarkup,
@@ -213,7 +216,9 @@ func (l *lexer) nextItem() item {
 
 // emitItem emits the given item and returns the next state function.
 func (l *lexer) emitItem(i item) stateFn {
+	i.pos, i.end = l.start-l.sigil, l.end
 	l.start = l.end
+	l.sigil = 0
 	l.item = i
 
 	if i.typ != itemWhitespace && i.typ != itemEOF {
@@ -237,11 +242,35 @@ func (l *lexer) val() string {
 	return ""
 }
 
+// skip skips the one byte sigil, e.g. "$" or ":", from the value of the item to be emitted.
+func (l *lexer) skip() {
+	l.start++
+	l.sigil++
+}
+
 // emitErrorf emits the error and returns the next state function.
 func (l *lexer) emitErrorf(s string, args ...any) stateFn {
 	return l.emitItem(mkErrorf(s, args...))
 }
 
+// recover skips the input after the error to continue lexing. Inside the expression the input is skipped
+// to the end of the expression "}", otherwise the lexing continues after the character causing the error.
+// It returns false if the end of the input is reached.
+func (l *lexer) recover() bool {
+	if l.isExpression {
+		if i := strings.IndexByte(l.input[l.end:], '}'); i >= 0 {
+			l.end += i
+		} else {
+			l.end = len(l.input)
+		}
+	}
+
+	l.start = l.end
+	l.line = 1 + strings.Count(l.input[:l.end], "\n")
+
+	return l.end < len(l.input)
+}
+
 // stateFn is a function that returns the next state function.
 type stateFn func(*lexer) stateFn
 
@@ -268,7 +297,7 @@ func lexStart(l *lexer) stateFn {
 		switch {
 		default:
 			return l.emitErrorf(`unexpected start char "%c"`, r)
-		case isWhitespace(r):
+		case isWhitespace(r), isBidi(r):
 		case isSimpleStart(r):
 			return simpleItem()
 		case r == '\\':
@@ -371,25 +400,25 @@ func lexComplexMessage(l *lexer) stateFn {
 			default:
 				return l.emitErrorf(`invalid keyword`)
 			case strings.HasPrefix(input, keywordLocal):
-				l.start++ // skip .
+				l.skip() // skip .
 				l.end += len(keywordLocal)
 
 				return l.emit(itemLocalKeyword)
 			case strings.HasPrefix(input, keywordInput):
-				l.start++ // skip .
+				l.skip() // skip .
 				l.end += len(keywordInput)
 
 				return l.emit(itemInputKeyword)
 			case strings.HasPrefix(input, keywordMatch):
-				l.start++ // skip .
+				l.skip() // skip .
 				l.end += len(keywordMatch)
 
 				return l.emit(itemMatchKeyword)
 			}
 		case r == variablePrefix:
-			l.start++ // skip $
+			l.skip() // skip $
 			return lexName(l, itemVariable)
-		case isWhitespace(r):
+		case isWhitespace(r), isBidi(r):
 			return lexWhitespace(l)
 		case r == '=':
 			return l.emit(itemOperator)
@@ -433,24 +462,24 @@ func lexExpr(l *lexer) stateFn {
 	default:
 		return l.emitErrorf(`bad character "%c" in expression`, r)
 	case r == variablePrefix:
-		l.start++ // skip $
+		l.skip() // skip $
 		return lexName(l, itemVariable)
 	case r == '|':
 		l.backup()
 		return lexQuotedLiteral(l)
 	case r == ':':
 		l.isFunction = true
-		l.start++ // skip :
+		l.skip() // skip :
 
 		return lexIdentifier(l, itemFunction)
 	case r == '@':
 		l.isFunction = false
-		l.start++ // skip @
+		l.skip() // skip @
 
 		return lexIdentifier(l, itemAttribute)
 	case r == '#':
 		l.isMarkup = true
-		l.start++ // skip #
+		l.skip() // skip #
 
 		return lexIdentifier(l, itemMarkupOpen)
 	case r == '/':
@@ -459,7 +488,7 @@ func lexExpr(l *lexer) stateFn {
 		}
 
 		l.isMarkup = true
-		l.start++ // skip /
+		l.skip() // skip /
 
 		return lexIdentifier(l, itemMarkupClose)
 	case r == '{': // expression/markup start
@@ -472,7 +501,7 @@ func lexExpr(l *lexer) stateFn {
 		l.isMarkup = false
 
 		return l.emit(itemExpressionClose)
-	case isWhitespace(r):
+	case isWhitespace(r), isBidi(r):
 		return lexWhitespace(l)
 	case (l.isFunction || l.isMarkup) && isNameStart(r):
 		l.backup()
@@ -538,8 +567,13 @@ func lexUnquotedLiteral(l *lexer) stateFn {
 	}
 }
 
-// lexWhitespace is the state function for lexing whitespace.
+// lexWhitespace is the state function for lexing whitespace with optional bidi marks.
 // The first character is already lexed.
+//
+// ABNF:
+//
+//	s = *bidi ws o
+//	o = *(ws / bidi)
 func lexWhitespace(l *lexer) stateFn {
 	for {
 		r := l.next()
@@ -547,74 +581,79 @@ func lexWhitespace(l *lexer) stateFn {
 		switch {
 		default:
 			l.backup()
-			return l.emit(itemWhitespace)
-		case isWhitespace(r):
+			return l.emitWhitespace()
+		case isWhitespace(r), isBidi(r):
 		case r == eof:
-			return l.emit(itemWhitespace)
+			return l.emitWhitespace()
 		}
 	}
 }
 
-// lexName is the state function for lexing names.
-func lexName(l *lexer, typ itemType) stateFn {
-	r := l.next()
-	if !isNameStart(r) {
-		return l.emitErrorf(`bad %s name "%s"`, typ, string(r))
+// emitWhitespace emits the whitespace item. Bidi marks without whitespace
+// are optional whitespace - they are skipped and the lexing continues.
+func (l *lexer) emitWhitespace() stateFn {
+	if strings.ContainsFunc(l.val(), isWhitespace) {
+		return l.emit(itemWhitespace)
 	}
 
-	for {
-		if r = l.next(); !isName(r) {
-			break
-		}
-	}
+	l.start = l.end
 
-	if r != eof {
-		l.backup()
+	return lexWhitespace
+}
+
+// lexName is the state function for lexing names.
+func lexName(l *lexer, typ itemType) stateFn {
+	if !l.acceptName() {
+		return l.emitErrorf(`bad %s name "%s"`, typ, string(l.next()))
 	}
 
-	return l.emit(typ)
+	return l.emitItem(mk(typ, stripBidi(l.val())))
 }
 
 // lexIdentifier is the state function for lexing identifiers.
 func lexIdentifier(l *lexer, typ itemType) stateFn {
-	r := l.next()
-	if !isNameStart(r) {
-		return l.emitErrorf(`bad %s identifier "%s"`, typ, string(r))
+	if !l.acceptName() {
+		return l.emitErrorf(`bad %s identifier "%s"`, typ, string(l.next()))
 	}
 
-	for {
-		if r = l.next(); !isName(r) {
-			break
+	// identifier with namespace
+	if l.peek() == ':' {
+		l.next()
+
+		if !l.acceptName() {
+			l.next()
+
+			return l.emitErrorf(`bad %s identifier "%s"`, typ, l.val())
 		}
 	}
 
-	switch r {
-	default:
-		l.backup()
+	return l.emitItem(mk(typ, stripBidi(l.val())))
+}
 
-		return l.emit(typ)
-	case ':': // identifier with namespace
-	case eof:
-		return l.emit(typ)
+// acceptName consumes the name with optional bidi marks around it.
+// It returns false if the name is not found.
+//
+// ABNF:
+//
+//	name = [bidi] name-start *name-char [bidi]
+func (l *lexer) acceptName() bool {
+	if isBidi(l.peek()) {
+		l.next()
 	}
 
-	r = l.next()
-
-	if !isNameStart(r) {
-		return l.emitErrorf(`bad %s identifier "%s"`, typ, l.val())
+	if !isNameStart(l.peek()) {
+		return false
 	}
 
-	for {
-		if r = l.next(); !isName(r) {
-			break
-		}
+	for isName(l.peek()) {
+		l.next()
 	}
 
-	if r != eof {
-		l.backup()
+	if isBidi(l.peek()) {
+		l.next()
 	}
 
-	return l.emit(typ)
+	return true
 }
 
 // helpers
@@ -706,6 +745,15 @@ func isName(v rune) bool {
 		v == '.'
 }
 
+// isUnquotedLiteral returns true if s can be written as unquoted literal.
+//
+// ABNF:
+//
+//	unquoted-literal = 1*name-char
+func isUnquotedLiteral(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return !isName(r) }) == -1
+}
+
 // isQuoted returns true if r is quoted character.
 //
 // ABNF:
@@ -728,14 +776,37 @@ func isWhitespace(r rune) bool {
 	switch r {
 	default:
 		return false
-	case '\u061C', '\u200E', '\u200F', '\u2066', '\u2067', '\u2068', '\u2069':
-		// TODO: should we separate it into `bidi`?
-		return true
 	case ' ', '\t', '\r', '\n', '\u3000':
 		return true
 	}
 }
 
+// isBidi returns true if r is bidirectional mark or isolate.
+//
+// ABNF:
+//
+//	; ALM / LRM / RLM / LRI, RLI, FSI & PDI
+//	bidi = %x061C / %x200E / %x200F / %x2066-2069
+func isBidi(r rune) bool {
+	switch r {
+	default:
+		return false
+	case '\u061C', '\u200E', '\u200F', '\u2066', '\u2067', '\u2068', '\u2069':
+		return true
+	}
+}
+
+// stripBidi removes bidi marks from the name. Bidi marks are not part of the name.
+func stripBidi(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isBidi(r) {
+			return -1
+		}
+
+		return r
+	}, s)
+}
+
 // isEscapedChar returns true if r is an escaped character.
 //
 // ABNF:
@@ -400,6 +400,28 @@ func Test_lex(t *testing.T) {
 				mk(itemEOF, ""),
 			},
 		},
+		{
+			name:  "bidi marks",
+			input: "\u200e .local $\u200efoo\u200f = {\u061cbar\u200e :\u200efn} {{}}\u2066",
+			want: []item{
+				mk(itemWhitespace, "\u200e "),
+				mk(itemLocalKeyword, "local"),
+				mk(itemWhitespace, " "),
+				mk(itemVariable, "foo"),
+				mk(itemWhitespace, " "),
+				mk(itemOperator, "="),
+				mk(itemWhitespace, " "),
+				mk(itemExpressionOpen, "{"),
+				mk(itemUnquotedLiteral, "bar"),
+				mk(itemWhitespace, "\u200e "),
+				mk(itemFunction, "fn"),
+				mk(itemExpressionClose, "}"),
+				mk(itemWhitespace, " "),
+				mk(itemQuotedPatternOpen, "{{"),
+				mk(itemQuotedPatternClose, "}}"),
+				mk(itemEOF, ""),
+			},
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
@@ -454,7 +476,7 @@ func assertItems(t *testing.T, want []item, l *lexer) {
 			return
 		}
 
-		if wantItem != gotItem {
+		if wantItem.typ != gotItem.typ || wantItem.val != gotItem.val {
 			logN(i + 1)
 			t.Fatalf(`want '%v', got '%v'`, wantItem, gotItem)
 		}
@@ -490,6 +512,8 @@ func logItem(t *testing.T, want item, l lexer) {
 func BenchmarkLex(b *testing.B) {
 	var itm item
 
+	b.ReportAllocs()
+
 	for range b.N {
 		lexer := lex(`
 .input {$foo :number @attribute=value}
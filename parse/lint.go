@@ -0,0 +1,260 @@
+package parse
+
+import "fmt"
+
+// LintRule identifies the check that reported the [LintIssue].
+type LintRule string
+
+// Lint rules.
+const (
+	// LintUnusedInput reports the input declaration that is never referenced.
+	LintUnusedInput LintRule = "unused-input"
+	// LintUnusedLocal reports the local declaration that is never referenced.
+	LintUnusedLocal LintRule = "unused-local"
+	// LintOptionOnlyLocal reports the local declaration that is referenced only as an option value.
+	LintOptionOnlyLocal LintRule = "option-only-local"
+	// LintRedundantSelector reports the selector that does not affect the selected pattern,
+	// i.e. the variants differing only by the key of the selector have the same pattern.
+	LintRedundantSelector LintRule = "redundant-selector"
+)
+
+// LintIssue is the issue reported by [Lint].
+type LintIssue struct {
+	Rule     LintRule
+	Variable Variable // the declared variable or the selector
+	Span     Span     // the span of the declaration or the matcher
+}
+
+// String returns the issue in the "line:column: description (rule)" format.
+func (i LintIssue) String() string {
+	var description string
+
+	switch i.Rule {
+	default:
+		description = "variable " + i.Variable.String()
+	case LintUnusedInput:
+		description = "unused input variable " + i.Variable.String()
+	case LintUnusedLocal:
+		description = "unused local variable " + i.Variable.String()
+	case LintOptionOnlyLocal:
+		description = "local variable " + i.Variable.String() + " is used only as an option value"
+	case LintRedundantSelector:
+		description = "selector " + i.Variable.String() + " does not affect the selected pattern"
+	}
+
+	if i.Span.IsZero() {
+		return fmt.Sprintf("%s (%s)", description, i.Rule)
+	}
+
+	return fmt.Sprintf("%s: %s (%s)", i.Span.Start, description, i.Rule)
+}
+
+/*
+Lint reports the unused declarations, the locals used only as option values and the redundant
+selectors of the complex message. The stale declarations typically remain after the message is edited.
+
+Duplicate and shadowing declarations are reported by [Parse] as [mf2.ErrDuplicateDeclaration].
+
+Example:
+
+	tree, _ := parse.Parse(".input {$count :number} .local $unused = {1} {{{$count}}}")
+
+	for _, issue := range parse.Lint(tree) {
+		fmt.Println(issue) // 1:25: unused local variable $unused (unused-local)
+	}
+*/
+func Lint(tree AST) []LintIssue {
+	message, ok := tree.Message.(ComplexMessage)
+	if !ok {
+		return nil
+	}
+
+	uses := variableUses(message)
+
+	var issues []LintIssue
+
+	for _, declaration := range message.Declarations {
+		switch d := declaration.(type) {
+		case InputDeclaration:
+			if variable, ok := d.Operand.(Variable); ok && uses[variable] == (variableUse{}) {
+				issues = append(issues, LintIssue{Rule: LintUnusedInput, Variable: variable, Span: d.Span})
+			}
+		case LocalDeclaration:
+			switch use := uses[d.Variable]; {
+			case use == (variableUse{}):
+				issues = append(issues, LintIssue{Rule: LintUnusedLocal, Variable: d.Variable, Span: d.Span})
+			case use.other == 0:
+				issues = append(issues, LintIssue{Rule: LintOptionOnlyLocal, Variable: d.Variable, Span: d.Span})
+			}
+		}
+	}
+
+	if matcher, ok := message.ComplexBody.(Matcher); ok {
+		for i, selector := range matcher.Selectors {
+			if isRedundantSelector(matcher, i) {
+				issues = append(issues, LintIssue{Rule: LintRedundantSelector, Variable: selector, Span: matcher.Span})
+			}
+		}
+	}
+
+	return issues
+}
+
+// variableUse counts the references of the variable.
+type variableUse struct {
+	option int // as an option value
+	other  int // as an operand, an attribute value or a selector
+}
+
+// variableUses returns the references of the variables, except the declarations.
+func variableUses(message ComplexMessage) map[Variable]variableUse {
+	uses := make(map[Variable]variableUse)
+
+	var inspect func(node Node) bool
+
+	inspect = func(node Node) bool {
+		switch n := node.(type) {
+		case InputDeclaration:
+			// the operand is the declared variable
+			if n.Annotation != nil {
+				Inspect(n.Annotation, inspect)
+			}
+
+			for _, attribute := range n.Attributes {
+				Inspect(attribute, inspect)
+			}
+
+			return false
+		case LocalDeclaration:
+			Inspect(n.Expression, inspect)
+			return false
+		case Option:
+			if variable, ok := n.Value.(Variable); ok {
+				use := uses[variable]
+				use.option++
+				uses[variable] = use
+			}
+
+			return false
+		case Variable:
+			use := uses[n]
+			use.other++
+			uses[n] = use
+		}
+
+		return true
+	}
+
+	Inspect(message, inspect)
+
+	return uses
+}
+
+// maxSelections limits the combinations of the selector values checked by [isRedundantSelector].
+const maxSelections = 1 << 16
+
+// isRedundantSelector returns true if the selector does not affect the selected pattern - for every
+// combination of the values of the other selectors, every value of the selector, matching one of its keys
+// or only the catch-all key, selects the same pattern.
+func isRedundantSelector(matcher Matcher, selector int) bool {
+	// keys are the distinct keys of the selectors, except the catch-all key
+	keys := make([][]string, len(matcher.Selectors))
+
+	for _, variant := range matcher.Variants {
+		if len(variant.Keys) != len(matcher.Selectors) {
+			return false
+		}
+
+		for i, key := range variant.Keys {
+			if _, ok := key.(CatchAllKey); !ok {
+				appendUnique(&keys[i], keyString(key))
+			}
+		}
+	}
+
+	selections := 1
+
+	for i := range keys {
+		if selections *= len(keys[i]) + 1; selections > maxSelections {
+			return false
+		}
+	}
+
+	// values are the indexes of the selector keys, len(keys[i]) is the value matching only the catch-all key
+	values := make([]int, len(keys))
+
+	for {
+		want, wantOK := selectVariant(matcher, keys, values)
+
+		for values[selector] = 1; values[selector] <= len(keys[selector]); values[selector]++ {
+			if got, ok := selectVariant(matcher, keys, values); got != want || ok != wantOK {
+				return false
+			}
+		}
+
+		values[selector] = 0
+
+		// the next combination of the values of the other selectors
+		i := 0
+
+		for ; i < len(values); i++ {
+			if i == selector {
+				continue
+			}
+
+			if values[i] < len(keys[i]) {
+				values[i]++
+				break
+			}
+
+			values[i] = 0
+		}
+
+		if i == len(values) {
+			return true
+		}
+	}
+}
+
+// selectVariant returns the pattern of the variant selected for the values, see [isRedundantSelector].
+// The variant with the exact key of the first selector is preferred to the catch-all key,
+// then the second selector, and so on. It returns false if no variant matches.
+func selectVariant(matcher Matcher, keys [][]string, values []int) (string, bool) {
+	var selected *Variant
+
+	for _, variant := range matcher.Variants {
+		matches := true
+		preferred := selected == nil
+
+		for i, key := range variant.Keys {
+			_, catchAll := key.(CatchAllKey)
+
+			if !catchAll && (values[i] == len(keys[i]) || keyString(key) != keys[i][values[i]]) {
+				matches = false
+				break
+			}
+
+			if preferred || selected == nil {
+				continue
+			}
+
+			// the first selector with the different keys decides
+			if _, selectedCatchAll := selected.Keys[i].(CatchAllKey); catchAll != selectedCatchAll {
+				preferred = !catchAll
+				if !preferred {
+					break
+				}
+			}
+		}
+
+		if matches && preferred {
+			selected = &variant
+		}
+	}
+
+	if selected == nil {
+		return "", false
+	}
+
+	return selected.QuotedPattern.String(), true
+}
@@ -0,0 +1,91 @@
+package parse
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "simple message",
+			input: "Hello, {$name}!",
+		},
+		{
+			name:  "used declarations",
+			input: ".input {$count :number} .local $n = {$count :integer} {{{$n}}}",
+		},
+		{
+			name:  "unused declarations",
+			input: ".input {$count :number}\n.local $unused = {1}\n.input {$name :string}\n{{{$name}}}",
+			want: []string{
+				"1:1: unused input variable $count (unused-input)",
+				"2:1: unused local variable $unused (unused-local)",
+			},
+		},
+		{
+			name:  "input used in option",
+			input: ".input {$digits :number} {{{1 :number minimumFractionDigits=$digits}}}",
+		},
+		{
+			name:  "local used only as option",
+			input: ".local $digits = {2} .local $x = {1 :number minimumFractionDigits=$digits} {{{$x :number minimumFractionDigits=$digits}}}",
+			want: []string{
+				"1:1: local variable $digits is used only as an option value (option-only-local)",
+			},
+		},
+		{
+			name:  "local used in selector",
+			input: ".local $n = {1 :number} .match $n one {{one}} * {{other}}",
+		},
+		{
+			name:  "redundant selector",
+			input: ".input {$n :number}\n.input {$g :string}\n.match $n $g\none male {{one}}\none * {{one}}\n* male {{other}}\n* * {{other}}",
+			want: []string{
+				"3:1: selector $g does not affect the selected pattern (redundant-selector)",
+			},
+		},
+		{
+			name:  "selectors affecting the pattern together",
+			input: ".input {$x :number} .input {$y :string} .match $x $y 1 a {{A}} * * {{B}}",
+		},
+		{
+			name:  "selector affecting the pattern of the catch-all key",
+			input: ".input {$n :number}\n.input {$g :string}\n.match $n $g\none male {{one}}\n* male {{other}}\n* * {{any}}",
+		},
+		{
+			name:  "all variants with the same pattern",
+			input: ".input {$n :number} .match $n one {{same}} * {{same}}",
+			want: []string{
+				"1:21: selector $n does not affect the selected pattern (redundant-selector)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, issue := range Lint(tree) {
+				got = append(got, issue.String())
+			}
+
+			if !slices.Equal(test.want, got) {
+				t.Errorf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -4,18 +4,40 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"go.expect.digital/mf2"
 	"golang.org/x/text/unicode/norm"
 )
 
 type parser struct {
-	reservedVariable Variable   // reservedVariable is a variable that cannot be re-declared within an expression
-	declaration      string     // input or local or empty
-	items            []item     // contains all lexed items
-	variables        []Variable // contains declared variable names
-	pos              int        // current position of lexed items
+	spans            *Spans      // records the spans of the nodes without the Span field
+	reservedVariable Variable    // reservedVariable is a variable that cannot be re-declared within an expression
+	declaration      string      // input or local or empty
+	input            string      // the parsed input
+	items            []item      // contains all lexed items
+	lines            []int       // byte offsets of the line starts in the input
+	variables        []Variable  // contains declared variable names
+	diagnostics      Diagnostics // errors reported in the recovery mode
+	pos              int         // current position of lexed items
+	balancedMarkup   bool        // requires balanced and properly nested markup
+	recover          bool        // recovers from errors and reports all of them
+}
+
+// ParseOption configures the parser. See [ParseWithOptions].
+type ParseOption func(p *parser)
+
+// RequireBalancedMarkup reports an error if the open and close markup in a pattern
+// are unbalanced or misnested, e.g. "{/b}text{#b}" or "{#i}{/b}". Standalone markup is ignored.
+//
+// The spec does not require the markup to be balanced, the option is intended to catch
+// broken tag pairs in the translations.
+func RequireBalancedMarkup() ParseOption {
+	return func(p *parser) {
+		p.balancedMarkup = true
+	}
 }
 
 func (p *parser) duplicateVariable(variable Variable) error {
@@ -36,7 +58,7 @@ func (p *parser) declareVariable(variable Variable) {
 func (p *parser) next() item {
 	// It can occur only when last item is not itemEOF. Lexer issue?
 	if p.pos == len(p.items)-1 {
-		return mk(itemError, "no more tokens")
+		return p.mkEnd(itemError, "no more tokens")
 	}
 
 	p.pos++
@@ -65,20 +87,195 @@ func (p *parser) peekNonWS() item {
 		}
 	}
 
-	return mk(itemError, "nothing to peek")
+	return p.mkEnd(itemError, "nothing to peek")
+}
+
+// mkEnd creates a new item at the end of the input.
+func (p *parser) mkEnd(typ itemType, val string) item {
+	itm := mk(typ, val)
+	itm.pos, itm.end = len(p.input), len(p.input)
+
+	return itm
 }
 
 func (p *parser) current() item {
 	return p.items[p.pos]
 }
 
+// position returns the position of the byte offset in the input.
+func (p *parser) position(offset int) Position {
+	offset = min(max(offset, 0), len(p.input))
+	line, _ := slices.BinarySearch(p.lines, offset+1) // the line starting at or before the offset
+
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: utf8.RuneCountInString(p.input[p.lines[line-1]:offset]) + 1,
+	}
+}
+
+// span returns the span of the given byte offsets in the input.
+func (p *parser) span(start, end int) Span {
+	return Span{Start: p.position(start), End: p.position(end)}
+}
+
+// spanFrom returns the span from the start offset to the end of the last parsed non-whitespace item.
+func (p *parser) spanFrom(start int) Span {
+	end := start
+
+	for i := min(p.pos, len(p.items)-1); i >= 0; i-- {
+		if itm := p.items[i]; itm.typ != itemWhitespace {
+			end = itm.end
+			break
+		}
+	}
+
+	return p.span(start, end)
+}
+
+// lineStarts returns the byte offsets of the line starts in the input.
+func lineStarts(input string) []int {
+	lines := []int{0}
+
+	for i := range len(input) {
+		if input[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return lines
+}
+
+// Recover makes the parser recover from errors and report all of them at once. [ParseWithOptions]
+// returns the partial AST and [Diagnostics] with every error found. The parser skips the invalid
+// input to the end of the expression "}", the end of the quoted pattern "}}", the next variant
+// or the next declaration, and continues.
+//
+// The partial AST is intended for the diagnostics only, it omits invalid expressions, markup,
+// declarations and variants, and the complex body might be missing.
+func Recover() ParseOption {
+	return func(p *parser) {
+		p.recover = true
+	}
+}
+
+// Diagnostics is the list of errors reported by [ParseWithOptions] with [Recover] option.
+type Diagnostics []error
+
+func (d Diagnostics) Error() string {
+	s := make([]string, len(d))
+
+	for i, err := range d {
+		s[i] = err.Error()
+	}
+
+	return strings.Join(s, "\n")
+}
+
+func (d Diagnostics) Unwrap() []error {
+	return d
+}
+
+// recoverFrom reports the error and skips the items with sync, if the parser recovers from errors.
+// It returns false if the parser does not recover, and the error must be returned.
+func (p *parser) recoverFrom(err error, sync func(p *parser)) bool {
+	if !p.recover {
+		return false
+	}
+
+	p.diagnostics = append(p.diagnostics, p.diagnostic(err))
+
+	if sync != nil {
+		sync(p)
+	}
+
+	return true
+}
+
+// diagnostic returns the error as [SyntaxError] with the position. The markup errors and
+// the data model errors, positioned at the current item, are wrapped.
+func (p *parser) diagnostic(err error) SyntaxError {
+	var (
+		syntaxErr SyntaxError
+		markupErr MarkupError
+	)
+
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr
+	}
+
+	position := p.position(p.current().pos)
+	description := err.Error()
+
+	if errors.As(err, &markupErr) {
+		position = markupErr.Position
+		description = fmt.Sprintf(`unbalanced markup "%s": %s`, markupErr.Identifier, markupErr.Reason)
+		err = markupErr
+	} else if _, after, ok := strings.Cut(description, mf2.ErrDataModel.Error()+": "); ok {
+		description = after
+	}
+
+	return SyntaxError{
+		Description: description,
+		Excerpt:     p.excerpt(position),
+		Position:    position,
+		Err:         err,
+	}
+}
+
+// syncExpression skips the items to the end of the expression or markup "}".
+// It stops before the end of the quoted pattern "}}" or the end of the input.
+func (p *parser) syncExpression() {
+	for itm := p.current(); itm.typ != itemExpressionClose; itm = p.next() {
+		if itm.typ == itemQuotedPatternClose || itm.typ == itemEOF {
+			p.backup()
+			return
+		}
+	}
+}
+
+// syncVariant skips the items to the end of the variant's quoted pattern "}}".
+// It stops before the end of the input.
+func (p *parser) syncVariant() {
+	for itm := p.current(); itm.typ != itemQuotedPatternClose; itm = p.next() {
+		if itm.typ == itemEOF {
+			p.backup()
+			return
+		}
+	}
+}
+
+// syncStatement skips the items to the next declaration, matcher, quoted pattern or the end of the input.
+func (p *parser) syncStatement() {
+	for {
+		switch p.peekNonWS().typ {
+		case itemInputKeyword, itemLocalKeyword, itemMatchKeyword, itemQuotedPatternOpen, itemEOF, itemError:
+			return
+		}
+
+		p.nextNonWS()
+	}
+}
+
 // TODO(jhorsts): add collect() to lex.go to be re-used here and unit tests.
 func (p *parser) collect(l *lexer) error {
 	// sanity check, avoid infinite loop
 	for range 10_000 {
 		itm := l.nextItem()
 		if itm.typ == itemError {
-			return fmt.Errorf("%w: %w", mf2.ErrSyntax, itm.err)
+			if !p.recover {
+				return p.syntaxErr(p.lexErrPos(itm), "%s", itm.err)
+			}
+
+			// the parser reports the error item as unexpected
+			p.items = append(p.items, itm)
+
+			if !l.recover() {
+				p.items = append(p.items, p.mkEnd(itemEOF, ""))
+				return nil
+			}
+
+			continue
 		}
 
 		p.items = append(p.items, itm)
@@ -148,19 +345,54 @@ Examples:
 	}
 */
 func Parse(input string) (AST, error) {
-	errorf := func(err error) (AST, error) {
+	return ParseWithOptions(input)
+}
+
+// ParseWithOptions parses the input string with the given options and returns an AST tree of MessageFormat2.
+//
+// Example:
+//
+//	parse.ParseWithOptions("{#b}Hello{/i}", parse.RequireBalancedMarkup())
+//	// result
+//	MarkupError{Identifier: Identifier{Name: "i"}, ...}
+func ParseWithOptions(input string, options ...ParseOption) (AST, error) {
+	p := newParser(input, options...)
+
+	message, err := p.parse()
+	if err != nil {
 		return AST{}, fmt.Errorf("parse MF2: %w", err)
 	}
 
-	p := &parser{pos: -1}
+	if p.spans != nil {
+		*p.spans = p.recordSpans(message)
+	}
 
-	err := p.collect(lex(input))
+	if len(p.diagnostics) > 0 {
+		return AST{Message: message}, fmt.Errorf("parse MF2: %w", p.diagnostics)
+	}
+
+	return AST{Message: message}, nil
+}
+
+func newParser(input string, options ...ParseOption) *parser {
+	p := &parser{pos: -1, input: input, lines: lineStarts(input)}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// parse lexes and parses the input. It returns nil message if the input is empty.
+func (p *parser) parse() (Message, error) {
+	err := p.collect(lex(p.input))
 	if err != nil {
-		return errorf(err)
+		return nil, err
 	}
 
 	if len(p.items) == 1 && p.items[0].typ == itemEOF {
-		return AST{}, nil
+		return nil, nil //nolint:nilnil
 	}
 
 	parse := func() (Message, error) { return p.parseSimpleMessage() }
@@ -170,14 +402,17 @@ func Parse(input string) (AST, error) {
 
 	message, err := parse()
 	if err != nil {
-		return errorf(err)
+		return nil, err
 	}
 
 	if itm := p.nextNonWS(); itm.typ != itemEOF {
-		return errorf(unexpectedErr(itm, itemEOF))
+		err := p.unexpectedErr(itm, itemEOF)
+		if !p.recoverFrom(err, nil) {
+			return nil, err
+		}
 	}
 
-	return AST{Message: message}, nil
+	return message, nil
 }
 
 // ------------------------------Message------------------------------
@@ -194,66 +429,98 @@ func (p *parser) parseSimpleMessage() (SimpleMessage, error) {
 func (p *parser) parseComplexMessage() (ComplexMessage, error) {
 	var message ComplexMessage
 
+	start := p.peekNonWS().pos
+
 	errorf := func(format string, args ...any) (ComplexMessage, error) {
 		return ComplexMessage{}, fmt.Errorf("complex message: "+format, args...)
 	}
 
-	// optional declarations
+	recoverFrom := func(err error, sync func(p *parser)) bool {
+		return p.recoverFrom(fmt.Errorf("complex message: %w", err), sync)
+	}
 
-declarationsLoop:
+messageLoop:
 	for {
-		switch itm := p.nextNonWS(); itm.typ {
-		default:
-			p.backup()
-			break declarationsLoop
-		case itemInputKeyword:
-			declaration, err := p.parseInputDeclaration()
-			if err != nil {
-				return errorf("%w", err)
+		// optional declarations
+
+	declarationsLoop:
+		for {
+			var (
+				declaration Declaration
+				err         error
+			)
+
+			switch itm := p.nextNonWS(); itm.typ {
+			default:
+				p.backup()
+				break declarationsLoop
+			case itemInputKeyword:
+				declaration, err = p.parseInputDeclaration()
+			case itemLocalKeyword:
+				declaration, err = p.parseLocalDeclaration()
 			}
 
-			message.Declarations = append(message.Declarations, declaration)
-		case itemLocalKeyword:
-			declaration, err := p.parseLocalDeclaration()
 			if err != nil {
+				if recoverFrom(err, (*parser).syncStatement) {
+					continue
+				}
+
 				return errorf("%w", err)
 			}
 
 			message.Declarations = append(message.Declarations, declaration)
 		}
-	}
 
-	// complex body
+		// complex body
 
-	switch itm := p.nextNonWS(); itm.typ {
-	default:
-		err := unexpectedErr(
-			itm, itemInputKeyword, itemLocalKeyword, itemMatchKeyword, itemQuotedPatternOpen,
-		)
+		switch itm := p.nextNonWS(); itm.typ {
+		default:
+			err := p.unexpectedErr(
+				itm, itemInputKeyword, itemLocalKeyword, itemMatchKeyword, itemQuotedPatternOpen,
+			)
+
+			if recoverFrom(err, (*parser).syncStatement) {
+				continue messageLoop
+			}
 
-		return errorf("%w", err)
-	case itemQuotedPatternOpen:
-		pattern, err := p.parsePattern()
-		if err != nil {
 			return errorf("%w", err)
-		}
+		case itemQuotedPatternOpen:
+			pattern, err := p.parsePattern()
+			if err != nil {
+				return errorf("%w", err)
+			}
 
-		if itm := p.next(); itm.typ != itemQuotedPatternClose {
-			return errorf("%w", unexpectedErr(itm, itemQuotedPatternClose))
-		}
+			if itm := p.next(); itm.typ != itemQuotedPatternClose {
+				err := p.unexpectedErr(itm, itemQuotedPatternClose)
+				if !recoverFrom(err, nil) {
+					return errorf("%w", err)
+				}
 
-		message.ComplexBody = QuotedPattern(pattern)
-	case itemMatchKeyword:
-		matcher, err := p.parseMatcher(message.Declarations)
-		if err != nil {
-			return errorf("%w", err)
+				p.backup()
+			}
+
+			message.ComplexBody = QuotedPattern(pattern)
+		case itemMatchKeyword:
+			matcher, err := p.parseMatcher(message.Declarations)
+			if err != nil {
+				return errorf("%w", err)
+			}
+
+			message.ComplexBody = matcher
+		case itemEOF:
+			err := errors.New("missing complex body")
+			if !recoverFrom(err, nil) {
+				return errorf("%w", err)
+			}
+
+			p.backup()
 		}
 
-		message.ComplexBody = matcher
-	case itemEOF:
-		return errorf("missing complex body")
+		break
 	}
 
+	message.Span = p.spanFrom(start)
+
 	return message, nil
 }
 
@@ -261,22 +528,45 @@ declarationsLoop:
 
 // parsePattern parses a slice of pattern parts.
 func (p *parser) parsePattern() ([]PatternPart, error) {
-	var pattern []PatternPart
+	var (
+		pattern []PatternPart
+		open    []openMarkup // open markup not closed yet, only if balanced markup is required
+	)
 
 	errorf := func(format string, args ...any) ([]PatternPart, error) {
 		return nil, fmt.Errorf("pattern: "+format, args...)
 	}
 
+	recoverFrom := func(err error, sync func(p *parser)) bool {
+		return p.recoverFrom(fmt.Errorf("pattern: %w", err), sync)
+	}
+
 	// Loop until the end, or closing pattern quote, if parsing complex message.
 	for {
 		switch itm := p.next(); itm.typ {
 		default:
-			err := unexpectedErr(itm, itemWhitespace, itemText, itemExpressionOpen, itemMarkupOpen, itemMarkupClose)
+			err := p.unexpectedErr(itm, itemWhitespace, itemText, itemExpressionOpen, itemMarkupOpen, itemMarkupClose)
+			if recoverFrom(err, nil) {
+				continue
+			}
+
 			return errorf("%w", err)
 		case itemWhitespace:
 			continue
 		case itemQuotedPatternClose, itemEOF:
 			p.backup()
+
+			if !p.balancedMarkup {
+				return pattern, nil
+			}
+
+			for _, unclosed := range open {
+				err := p.markupErr(unclosed.markup, unclosed.pos, "not closed")
+				if !recoverFrom(err, nil) {
+					return errorf("%w", err)
+				}
+			}
+
 			return pattern, nil
 		case itemText:
 			pattern = append(pattern, Text(itm.val))
@@ -285,9 +575,24 @@ func (p *parser) parsePattern() ([]PatternPart, error) {
 			if typ := p.peekNonWS().typ; typ == itemMarkupOpen || typ == itemMarkupClose {
 				markup, err := p.parseMarkup()
 				if err != nil {
+					if recoverFrom(err, (*parser).syncExpression) {
+						continue
+					}
+
 					return errorf("%w", err)
 				}
 
+				if p.balancedMarkup {
+					balanced, err := p.balanceMarkup(open, markup, itm.pos)
+					if err != nil && !recoverFrom(err, nil) {
+						return errorf("%w", err)
+					}
+
+					if err == nil {
+						open = balanced
+					}
+				}
+
 				pattern = append(pattern, markup)
 
 				continue
@@ -295,6 +600,10 @@ func (p *parser) parsePattern() ([]PatternPart, error) {
 
 			expression, err := p.parseExpression()
 			if err != nil {
+				if recoverFrom(err, (*parser).syncExpression) {
+					continue
+				}
+
 				return errorf("%w", err)
 			}
 
@@ -305,16 +614,56 @@ func (p *parser) parsePattern() ([]PatternPart, error) {
 
 // --------------------------------Markup--------------------------------
 
+// openMarkup is the open markup and its byte offset in the input.
+type openMarkup struct {
+	markup Markup
+	pos    int
+}
+
+// balanceMarkup pushes the open markup to the stack, and pops the matching open markup for the close markup.
+func (p *parser) balanceMarkup(open []openMarkup, markup Markup, pos int) ([]openMarkup, error) {
+	switch markup.Typ {
+	default: // standalone
+		return open, nil
+	case Open:
+		return append(open, openMarkup{markup: markup, pos: pos}), nil
+	case Close:
+		if len(open) == 0 {
+			return nil, p.markupErr(markup, pos, "closed without being opened")
+		}
+
+		last := open[len(open)-1]
+		if last.markup.Identifier.String() != markup.Identifier.String() {
+			reason := fmt.Sprintf(`misnested, open markup "%s" at %s is not closed`, last.markup.Identifier, p.position(last.pos))
+
+			return nil, p.markupErr(markup, pos, reason)
+		}
+
+		return open[:len(open)-1], nil
+	}
+}
+
+// markupErr returns [MarkupError] for the markup at the given byte offset.
+func (p *parser) markupErr(markup Markup, pos int, reason string) error {
+	return MarkupError{
+		Identifier: markup.Identifier,
+		Reason:     reason,
+		Position:   p.position(pos),
+	}
+}
+
 func (p *parser) parseMarkup() (Markup, error) {
 	var markup Markup
 
+	start := p.current().pos // {
+
 	errorf := func(format string, args ...any) (Markup, error) {
 		return Markup{}, fmt.Errorf("markup: "+format, args...)
 	}
 
 	switch itm := p.nextNonWS(); itm.typ {
 	default:
-		err := unexpectedErr(itm, itemMarkupOpen, itemMarkupClose)
+		err := p.unexpectedErr(itm, itemMarkupOpen, itemMarkupClose)
 		return errorf("open item: %w", err)
 	case itemMarkupOpen:
 		markup.Typ = Open
@@ -330,7 +679,7 @@ optionsLoop:
 	for {
 		switch itm := p.nextNonWS(); itm.typ {
 		default:
-			err := unexpectedErr(itm, itemOption, itemAttribute, itemMarkupClose, itemExpressionClose)
+			err := p.unexpectedErr(itm, itemOption, itemAttribute, itemMarkupClose, itemExpressionClose)
 			return errorf("options: %w", err)
 		case itemOption:
 			option, err := p.parseOption()
@@ -343,6 +692,8 @@ optionsLoop:
 			p.backup()
 			break optionsLoop
 		case itemExpressionClose:
+			markup.Span = p.spanFrom(start)
+
 			return markup, nil
 		case itemMarkupClose:
 			if markup.Typ == Close {
@@ -350,11 +701,13 @@ optionsLoop:
 			}
 
 			if itm := p.next(); itm.typ != itemExpressionClose {
-				return errorf("%w", unexpectedErr(itm, itemExpressionClose))
+				return errorf("%w", p.unexpectedErr(itm, itemExpressionClose))
 			}
 
 			markup.Typ = SelfClose
 
+			markup.Span = p.spanFrom(start)
+
 			return markup, nil
 		}
 	}
@@ -364,7 +717,7 @@ optionsLoop:
 	for {
 		switch itm := p.nextNonWS(); itm.typ {
 		default:
-			err := unexpectedErr(itm, itemAttribute, itemMarkupClose, itemExpressionClose)
+			err := p.unexpectedErr(itm, itemAttribute, itemMarkupClose, itemExpressionClose)
 			return errorf("%w", err)
 		case itemAttribute:
 			attribute, err := p.parseAttribute()
@@ -374,6 +727,8 @@ optionsLoop:
 
 			markup.Attributes = append(markup.Attributes, attribute)
 		case itemExpressionClose:
+			markup.Span = p.spanFrom(start)
+
 			return markup, nil
 		case itemMarkupClose:
 			if markup.Typ == Close {
@@ -381,11 +736,13 @@ optionsLoop:
 			}
 
 			if itm := p.next(); itm.typ != itemExpressionClose {
-				return errorf("%w", unexpectedErr(itm, itemExpressionClose))
+				return errorf("%w", p.unexpectedErr(itm, itemExpressionClose))
 			}
 
 			markup.Typ = SelfClose
 
+			markup.Span = p.spanFrom(start)
+
 			return markup, nil
 		}
 	}
@@ -403,11 +760,13 @@ func (p *parser) parseExpression() (Expression, error) {
 		return Expression{}, fmt.Errorf("expression: "+format, args...)
 	}
 
+	start := p.current().pos // {
+
 	// optional operand - literal or variable
 
 	switch itm := p.nextNonWS(); itm.typ {
 	default:
-		err = unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral, itemFunction, itemExpressionClose)
+		err = p.unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral, itemFunction, itemExpressionClose)
 
 		return errorf("%w: %w", mf2.ErrBadOperand, err)
 	case itemVariable:
@@ -441,18 +800,21 @@ func (p *parser) parseExpression() (Expression, error) {
 	case itemFunction:
 		p.backup()
 	case itemExpressionClose: // empty expression
-		return errorf("%w: empty expression", mf2.ErrSyntax)
+		return errorf("%w", p.syntaxErr(itm.pos, "empty expression"))
 	}
 
 	if p.peekNonWS().typ == itemExpressionClose { // expression with operand only
 		p.nextNonWS()
+
+		expr.Span = p.spanFrom(start)
+
 		return expr, nil
 	}
 
 	// ensure whitespace follows operand before annotation
 	if expr.Operand != nil {
 		if itm := p.next(); itm.typ != itemWhitespace {
-			return errorf("between operand and annotation: %w", unexpectedErr(itm, itemWhitespace))
+			return errorf("between operand and annotation: %w", p.unexpectedErr(itm, itemWhitespace))
 		}
 	}
 
@@ -460,7 +822,7 @@ func (p *parser) parseExpression() (Expression, error) {
 
 	switch itm := p.next(); itm.typ {
 	default:
-		return errorf("%w", unexpectedErr(itm, itemFunction, itemAttribute))
+		return errorf("%w", p.unexpectedErr(itm, itemFunction, itemAttribute))
 	case itemFunction:
 		expr.Annotation, err = p.parseFunction()
 		if err != nil {
@@ -477,12 +839,15 @@ func (p *parser) parseExpression() (Expression, error) {
 
 	if p.peekNonWS().typ == itemExpressionClose {
 		p.nextNonWS()
+
+		expr.Span = p.spanFrom(start)
+
 		return expr, nil
 	}
 
 	// ensure whitespace follows annotation before attributes
 	if itm := p.next(); itm.typ != itemWhitespace {
-		return errorf("%w", unexpectedErr(itm, itemWhitespace))
+		return errorf("%w", p.unexpectedErr(itm, itemWhitespace))
 	}
 
 	// parse attributes
@@ -492,18 +857,22 @@ func (p *parser) parseExpression() (Expression, error) {
 	}
 
 	if itm := p.nextNonWS(); itm.typ != itemExpressionClose {
-		return errorf("%w", unexpectedErr(itm, itemExpressionClose))
+		return errorf("%w", p.unexpectedErr(itm, itemExpressionClose))
 	}
 
+	expr.Span = p.spanFrom(start)
+
 	return expr, nil
 }
 
 // ------------------------------Annotation------------------------------
 
 func (p *parser) parseFunction() (Function, error) {
+	start := p.current().pos
 	function := Function{Identifier: p.parseIdentifier()}
 
 	if p.peekNonWS().typ == itemExpressionClose {
+		function.Span = p.spanFrom(start)
 		return function, nil
 	}
 
@@ -516,12 +885,12 @@ func (p *parser) parseFunction() (Function, error) {
 
 	for {
 		if itm := p.next(); itm.typ != itemWhitespace {
-			return errorf("%w", unexpectedErr(itm, itemWhitespace))
+			return errorf("%w", p.unexpectedErr(itm, itemWhitespace))
 		}
 
 		switch itm := p.next(); itm.typ {
 		default:
-			return errorf("%w", unexpectedErr(itm, itemOption, itemExpressionClose, itemAttribute))
+			return errorf("%w", p.unexpectedErr(itm, itemOption, itemExpressionClose, itemAttribute))
 		case itemOption:
 			option, err := p.parseOption()
 			if err != nil {
@@ -539,10 +908,13 @@ func (p *parser) parseFunction() (Function, error) {
 			p.backup()
 			p.backup() // whitespace
 
+			function.Span = p.spanFrom(start)
+
 			return function, nil
 		}
 
 		if p.peekNonWS().typ == itemExpressionClose {
+			function.Span = p.spanFrom(start)
 			return function, nil
 		}
 	}
@@ -559,13 +931,14 @@ func (p *parser) parseLocalDeclaration() (LocalDeclaration, error) {
 
 	defer func() { p.declaration = "" }()
 
+	start := p.current().pos
 	next := p.next()
 	if next.typ != itemWhitespace {
-		return errorf(unexpectedErr(next, itemWhitespace))
+		return errorf(p.unexpectedErr(next, itemWhitespace))
 	}
 
 	if next = p.next(); next.typ != itemVariable {
-		return errorf(unexpectedErr(next, itemVariable))
+		return errorf(p.unexpectedErr(next, itemVariable))
 	}
 
 	variable := Variable(norm.NFC.String(next.val))
@@ -584,11 +957,11 @@ func (p *parser) parseLocalDeclaration() (LocalDeclaration, error) {
 	declaration := LocalDeclaration{Variable: variable}
 
 	if next = p.nextNonWS(); next.typ != itemOperator {
-		return errorf(unexpectedErr(next, itemOperator))
+		return errorf(p.unexpectedErr(next, itemOperator))
 	}
 
 	if next = p.nextNonWS(); next.typ != itemExpressionOpen {
-		return errorf(unexpectedErr(next, itemExpressionOpen))
+		return errorf(p.unexpectedErr(next, itemExpressionOpen))
 	}
 
 	expression, err := p.parseExpression()
@@ -597,6 +970,7 @@ func (p *parser) parseLocalDeclaration() (LocalDeclaration, error) {
 	}
 
 	declaration.Expression = expression
+	declaration.Span = p.spanFrom(start)
 
 	return declaration, nil
 }
@@ -610,9 +984,10 @@ func (p *parser) parseInputDeclaration() (InputDeclaration, error) {
 
 	defer func() { p.declaration = "" }()
 
+	start := p.current().pos
 	next := p.nextNonWS()
 	if next.typ != itemExpressionOpen {
-		return errorf("%w", unexpectedErr(next, itemExpressionOpen))
+		return errorf("%w", p.unexpectedErr(next, itemExpressionOpen))
 	}
 
 	expression, err := p.parseExpression()
@@ -620,6 +995,9 @@ func (p *parser) parseInputDeclaration() (InputDeclaration, error) {
 		return errorf("%w", err)
 	}
 
+	// the span of the input declaration includes the keyword
+	expression.Span = p.spanFrom(start)
+
 	return InputDeclaration(expression), nil
 }
 
@@ -627,10 +1005,8 @@ func (p *parser) parseInputDeclaration() (InputDeclaration, error) {
 
 // isFallback returns true if all keys are "*".
 func isFallback(keys []VariantKey) bool {
-	fallbackVariant := CatchAllKey{}
-
 	for _, key := range keys {
-		if key != fallbackVariant {
+		if _, ok := key.(CatchAllKey); !ok {
 			return false
 		}
 	}
@@ -665,7 +1041,7 @@ func hasAnnotation(variable Variable, declarations []Declaration) bool {
 	return false
 }
 
-//nolint:gocognit
+//nolint:gocognit,gocyclo,cyclop
 func (p *parser) parseMatcher(declarations []Declaration) (Matcher, error) {
 	var matcher Matcher
 
@@ -673,13 +1049,30 @@ func (p *parser) parseMatcher(declarations []Declaration) (Matcher, error) {
 		return Matcher{}, fmt.Errorf("matcher: "+format, args...)
 	}
 
+	recoverFrom := func(err error, sync func(p *parser)) bool {
+		return p.recoverFrom(fmt.Errorf("matcher: %w", err), sync)
+	}
+
+	start := p.current().pos
+
 	// parse one or more selectors
 
+	selectorsErr := false
+
 selectorsLoop:
 	for {
 		itm := p.next()
 		if itm.typ != itemWhitespace {
-			return errorf("missing whitespace before selector: %w", unexpectedErr(itm, itemWhitespace))
+			err := fmt.Errorf("missing whitespace before selector: %w", p.unexpectedErr(itm, itemWhitespace))
+			if !recoverFrom(err, nil) {
+				return errorf("%w", err)
+			}
+
+			p.backup()
+
+			selectorsErr = true
+
+			break selectorsLoop
 		}
 
 		switch itm := p.next(); itm.typ {
@@ -687,10 +1080,19 @@ selectorsLoop:
 			p.backup()
 			break selectorsLoop
 		case itemEOF:
-			return errorf("%w", unexpectedErr(itm))
+			err := p.unexpectedErr(itm)
+			if !recoverFrom(err, nil) {
+				return errorf("%w", err)
+			}
+
+			p.backup()
+
+			matcher.Span = p.spanFrom(start)
+
+			return matcher, nil
 		case itemVariable:
 			v := Variable(norm.NFC.String(itm.val))
-			if !hasAnnotation(v, declarations) {
+			if !hasAnnotation(v, declarations) && !recoverFrom(mf2.ErrMissingSelectorAnnotation, nil) {
 				return errorf("%w", mf2.ErrMissingSelectorAnnotation)
 			}
 
@@ -698,13 +1100,17 @@ selectorsLoop:
 		}
 	}
 
-	if len(matcher.Selectors) == 0 {
-		return errorf("%w: missing selector", mf2.ErrSyntax)
-	}
-
-	if v := p.current(); v.typ != itemWhitespace {
+	if !selectorsErr && len(matcher.Selectors) == 0 {
+		err := p.syntaxErr(p.current().pos, "missing selector")
+		if !recoverFrom(err, nil) {
+			return errorf("%w", err)
+		}
+	} else if v := p.current(); !selectorsErr && v.typ != itemWhitespace {
 		// there should be a whitespace between selectors and variants
-		return errorf("missing whitespace between selectors and variants: %w", mf2.ErrSyntax)
+		err := p.syntaxErr(v.pos, "missing whitespace between selectors and variants")
+		if !recoverFrom(err, nil) {
+			return errorf("%w", err)
+		}
 	}
 
 	// parse one or more variants
@@ -713,41 +1119,61 @@ selectorsLoop:
 	for {
 		switch itm := p.nextNonWS(); itm.typ {
 		default:
-			err := unexpectedErr(itm, itemCatchAllKey, itemQuotedLiteral, itemUnquotedLiteral)
+			err := p.unexpectedErr(itm, itemCatchAllKey, itemQuotedLiteral, itemUnquotedLiteral)
+			if recoverFrom(err, (*parser).syncVariant) {
+				continue
+			}
+
 			return errorf("%w", err)
 		case itemEOF:
 			p.backup()
 
 			// fallback variant is required
-			for i := range matcher.Variants {
-				if isFallback(matcher.Variants[i].Keys) {
-					return matcher, nil
-				}
+			if !slices.ContainsFunc(matcher.Variants, func(v Variant) bool { return isFallback(v.Keys) }) &&
+				!recoverFrom(mf2.ErrMissingFallbackVariant, nil) {
+				return errorf("%w", mf2.ErrMissingFallbackVariant)
 			}
 
-			return errorf("%w", mf2.ErrMissingFallbackVariant)
+			matcher.Span = p.spanFrom(start)
+
+			return matcher, nil
 		case itemCatchAllKey, itemQuotedLiteral, itemUnquotedLiteral:
+			variantStart := itm.pos
+
 			keys, err := p.parseVariantKeys()
 			if err != nil {
+				if recoverFrom(err, (*parser).syncVariant) {
+					continue
+				}
+
 				return errorf("%w", err)
 			}
 
+			valid := true // invalid variant is not added to the partial AST
+
 			if len(keys) != len(matcher.Selectors) {
-				return errorf("%w: %d selectors and %d keys", mf2.ErrVariantKeyMismatch, len(matcher.Selectors), len(keys))
+				err := fmt.Errorf("%w: %d selectors and %d keys", mf2.ErrVariantKeyMismatch, len(matcher.Selectors), len(keys))
+				if !recoverFrom(err, nil) {
+					return errorf("%w", err)
+				}
+
+				valid = false
 			}
 
-			for _, v := range keysLookup {
-				if slices.EqualFunc(v, keys, func(a, b VariantKey) bool {
+			if slices.ContainsFunc(keysLookup, func(v []VariantKey) bool {
+				return slices.EqualFunc(v, keys, func(a, b VariantKey) bool {
 					_, okA := a.(CatchAllKey)
 					_, okB := b.(CatchAllKey)
 
 					return okA == okB && keyString(a) == keyString(b)
-				}) {
+				})
+			}) {
+				if !recoverFrom(mf2.ErrDuplicateVariant, nil) {
 					return errorf("%w", mf2.ErrDuplicateVariant)
 				}
-			}
 
-			keysLookup = append(keysLookup, keys)
+				valid = false
+			}
 
 			pattern, err := p.parsePattern()
 			if err != nil {
@@ -755,10 +1181,26 @@ selectorsLoop:
 			}
 
 			if itm := p.next(); itm.typ != itemQuotedPatternClose {
-				return errorf("variant pattern: %w", unexpectedErr(itm, itemExpressionClose))
+				err := fmt.Errorf("variant pattern: %w", p.unexpectedErr(itm, itemQuotedPatternClose))
+				if !recoverFrom(err, nil) {
+					return errorf("%w", err)
+				}
+
+				p.backup()
+
+				valid = false
 			}
 
-			matcher.Variants = append(matcher.Variants, Variant{Keys: keys, QuotedPattern: QuotedPattern(pattern)})
+			if !valid {
+				continue
+			}
+
+			keysLookup = append(keysLookup, keys)
+			matcher.Variants = append(matcher.Variants, Variant{
+				Keys:          keys,
+				QuotedPattern: QuotedPattern(pattern),
+				Span:          p.spanFrom(variantStart),
+			})
 		}
 	}
 }
@@ -776,21 +1218,22 @@ func (p *parser) parseVariantKeys() ([]VariantKey, error) {
 	for itm := p.current(); itm.typ != itemQuotedPatternOpen; itm = p.next() {
 		switch itm.typ {
 		default:
-			err := unexpectedErr(itm, itemWhitespace, itemCatchAllKey, itemQuotedLiteral, itemUnquotedLiteral)
+			err := p.unexpectedErr(itm, itemWhitespace, itemCatchAllKey, itemQuotedLiteral, itemUnquotedLiteral)
 			return errorf("%w", err)
 		case itemWhitespace:
 			spaced = true
 			continue
 		case itemCatchAllKey:
 			if !spaced && len(keys) > 0 {
-				return errorf("%w: missing space between keys %v and *", mf2.ErrSyntax, keys[len(keys)-1])
+				return errorf("%w", p.syntaxErr(itm.pos, "missing space between keys %v and *", keys[len(keys)-1]))
 			}
 
-			keys = append(keys, CatchAllKey{})
+			keys = append(keys, CatchAllKey{Span: p.span(itm.pos, itm.end)})
 			spaced = false
 		case itemQuotedLiteral, itemUnquotedLiteral:
 			if !spaced && len(keys) > 0 {
-				return errorf("%w: missing space between keys %v and %s", mf2.ErrSyntax, keys[len(keys)-1], itm.val)
+				err := p.syntaxErr(itm.pos, "missing space between keys %v and %s", keys[len(keys)-1], itm.val)
+				return errorf("%w", err)
 			}
 
 			literal, err := p.parseLiteral()
@@ -807,6 +1250,7 @@ func (p *parser) parseVariantKeys() ([]VariantKey, error) {
 }
 
 func (p *parser) parseOption() (Option, error) {
+	start := p.current().pos
 	option := Option{Identifier: p.parseIdentifier()}
 	errorf := func(format string, args ...any) (Option, error) {
 		return Option{}, fmt.Errorf("option: "+format, args...)
@@ -814,13 +1258,13 @@ func (p *parser) parseOption() (Option, error) {
 
 	// Next token must be an operator.
 	if next := p.nextNonWS(); next.typ != itemOperator {
-		return errorf("%w", unexpectedErr(next, itemOperator))
+		return errorf("%w", p.unexpectedErr(next, itemOperator))
 	}
 
 	// Next after operator must be a variable or literal.
 	switch next := p.nextNonWS(); next.typ {
 	default:
-		err := unexpectedErr(next, itemVariable, itemQuotedLiteral, itemUnquotedLiteral)
+		err := p.unexpectedErr(next, itemVariable, itemQuotedLiteral, itemUnquotedLiteral)
 		return errorf("%w", err)
 	case itemVariable:
 		variable := Variable(next.val)
@@ -839,6 +1283,8 @@ func (p *parser) parseOption() (Option, error) {
 		}
 	}
 
+	option.Span = p.spanFrom(start)
+
 	return option, nil
 }
 
@@ -852,13 +1298,13 @@ func (p *parser) parseAttributes() ([]Attribute, error) {
 	for {
 		if len(attributes) > 0 {
 			if itm := p.next(); itm.typ != itemWhitespace {
-				return errorf("%w", unexpectedErr(itm, itemWhitespace))
+				return errorf("%w", p.unexpectedErr(itm, itemWhitespace))
 			}
 		}
 
 		itm := p.next()
 		if itm.typ != itemAttribute {
-			return errorf("%w", unexpectedErr(itm, itemAttribute, itemExpressionClose))
+			return errorf("%w", p.unexpectedErr(itm, itemAttribute, itemExpressionClose))
 		}
 
 		attribute, err := p.parseAttribute()
@@ -875,6 +1321,7 @@ func (p *parser) parseAttributes() ([]Attribute, error) {
 }
 
 func (p *parser) parseAttribute() (Attribute, error) {
+	start := p.current().pos
 	attribute := Attribute{Identifier: p.parseIdentifier()}
 	errorf := func(format string, args ...any) (Attribute, error) {
 		return Attribute{}, fmt.Errorf("attribute: "+format, args...)
@@ -882,10 +1329,11 @@ func (p *parser) parseAttribute() (Attribute, error) {
 
 	switch itm := p.peekNonWS(); itm.typ {
 	default:
-		return errorf("%w", unexpectedErr(itm, itemAttribute, itemOperator, itemExpressionClose))
+		return errorf("%w", p.unexpectedErr(itm, itemAttribute, itemOperator, itemExpressionClose))
 	case itemOperator:
 		p.nextNonWS() // skip it
 	case itemExpressionClose, itemAttribute:
+		attribute.Span = p.spanFrom(start)
 		return attribute, nil
 	}
 
@@ -893,7 +1341,7 @@ func (p *parser) parseAttribute() (Attribute, error) {
 
 	switch itm := p.nextNonWS(); itm.typ {
 	default:
-		return errorf("%w", unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral))
+		return errorf("%w", p.unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral))
 	case itemQuotedLiteral, itemUnquotedLiteral:
 		attribute.Value, err = p.parseLiteral()
 		if err != nil {
@@ -901,13 +1349,15 @@ func (p *parser) parseAttribute() (Attribute, error) {
 		}
 	}
 
+	attribute.Span = p.spanFrom(start)
+
 	return attribute, nil
 }
 
 func (p *parser) parseLiteral() (Literal, error) {
 	switch itm := p.current(); itm.typ {
 	default:
-		err := unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral)
+		err := p.unexpectedErr(itm, itemQuotedLiteral, itemUnquotedLiteral)
 		return nil, fmt.Errorf("literal: %w", err)
 	case itemQuotedLiteral:
 		return QuotedLiteral(itm.val), nil
@@ -917,45 +1367,223 @@ func (p *parser) parseLiteral() (Literal, error) {
 }
 
 func (p *parser) parseIdentifier() Identifier {
-	split := strings.Split(p.current().val, ":") // namespace:name
+	itm := p.current()
+
+	start := itm.pos
+	if itm.typ != itemOption {
+		start++ // skip the sigil, e.g. ":" or "@"
+	}
+
+	span := p.span(start, itm.end)
+	split := strings.Split(itm.val, ":") // namespace:name
 
 	if len(split) == 1 {
-		return Identifier{Name: split[0]}
+		return Identifier{Name: split[0], Span: span}
+	}
+
+	return Identifier{Namespace: split[0], Name: split[1], Span: span}
+}
+
+// SyntaxError is returned when the input is not a well-formed MF2 message. It wraps [mf2.ErrSyntax].
+//
+// With [Recover] option, every diagnostic is SyntaxError. The markup and data model errors
+// are reported with their position, and wrap the original error, e.g. [MarkupError]
+// or [mf2.ErrDuplicateDeclaration].
+//
+// Example:
+//
+//	_, err := parse.Parse("Hello, {$name!")
+//
+//	var syntaxErr parse.SyntaxError
+//	if errors.As(err, &syntaxErr) {
+//		fmt.Println(syntaxErr.Excerpt)
+//		// Hello, {$name!
+//		//              ^
+//	}
+type SyntaxError struct {
+	// The wrapped markup or data model error, or nil.
+	Err error
+	// The description of the error, if the expected items are not known.
+	Description string
+	// The human-readable found item, e.g. `"}"`, `variable "x"` or "end of input".
+	Found string
+	// The line of the input with the caret pointing at the position of the error.
+	Excerpt string
+	// The human-readable expected items, e.g. `"}"`, "whitespace" or "literal".
+	Expected []string
+	// The position of the error in the input.
+	Position Position
+}
+
+func (e SyntaxError) Error() string {
+	kind := mf2.ErrSyntax
+	if errors.Is(e.Err, mf2.ErrDataModel) {
+		kind = mf2.ErrDataModel
+	}
+
+	prefix := kind.Error() + " at " + e.Position.String() + ": "
+
+	switch {
+	case e.Description != "":
+		return prefix + e.Description
+	case len(e.Expected) == 0:
+		return prefix + "unexpected " + e.Found
+	default:
+		return prefix + "want " + joinOr(e.Expected) + ", got " + e.Found
 	}
+}
 
-	return Identifier{Namespace: split[0], Name: split[1]}
+func (e SyntaxError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+
+	return mf2.ErrSyntax
 }
 
 // UnexpectedTokenError is returned when parser encounters unexpected token.
-// It contains information about expected token types and actual token type.
-type UnexpectedTokenError struct {
-	expected []itemType
-	actual   item
+//
+// Deprecated: Use [SyntaxError], it describes the unexpected token with its position and the excerpt.
+type UnexpectedTokenError = SyntaxError
+
+// syntaxErr returns [SyntaxError] at the byte offset in the input.
+func (p *parser) syntaxErr(offset int, format string, args ...any) error {
+	position := p.position(offset)
+
+	return SyntaxError{
+		Description: fmt.Sprintf(format, args...),
+		Excerpt:     p.excerpt(position),
+		Position:    position,
+	}
 }
 
-func (u UnexpectedTokenError) Error() string {
-	if len(u.expected) == 0 {
-		return "want no items, got " + u.actual.String()
+// lexErrPos returns the byte offset of the lexer error item - the last character lexed before the error.
+func (p *parser) lexErrPos(itm item) int {
+	_, size := utf8.DecodeLastRuneInString(p.input[:itm.end])
+
+	return max(itm.pos, itm.end-size)
+}
+
+// unexpectedErr returns [SyntaxError] for the unexpected item.
+func (p *parser) unexpectedErr(actual item, expected ...itemType) error {
+	// lexer error in the recovery mode
+	if actual.typ == itemError && actual.err != nil {
+		return p.syntaxErr(p.lexErrPos(actual), "%s", actual.err)
+	}
+
+	position := p.position(actual.pos)
+	err := SyntaxError{
+		Found:    describeItem(actual),
+		Excerpt:  p.excerpt(position),
+		Position: position,
+	}
+
+	for _, typ := range expected {
+		if s := describeItemType(typ); !slices.Contains(err.Expected, s) {
+			err.Expected = append(err.Expected, s)
+		}
+	}
+
+	return err
+}
+
+// excerpt returns the line of the input at the position, and the caret pointing at the position.
+func (p *parser) excerpt(position Position) string {
+	start := p.lines[position.Line-1]
+
+	line := p.input[start:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	line = strings.TrimSuffix(line, "\r")
+
+	// keep tabs to align the caret
+	var caret strings.Builder
+
+	for _, r := range p.input[start:position.Offset] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
 	}
 
-	var sb strings.Builder
+	return line + "\n" + caret.String() + "^"
+}
+
+// describeItemType returns the human-readable item type.
+func describeItemType(typ itemType) string {
+	switch typ {
+	default:
+		return typ.String()
+	case itemEOF:
+		return "end of input"
+	case itemExpressionOpen:
+		return `"{"`
+	case itemExpressionClose:
+		return `"}"`
+	case itemQuotedPatternOpen:
+		return `"{{"`
+	case itemQuotedPatternClose:
+		return `"}}"`
+	case itemOperator:
+		return `"="`
+	case itemCatchAllKey:
+		return `"*"`
+	case itemInputKeyword:
+		return `".` + keywordInput + `"`
+	case itemLocalKeyword:
+		return `".` + keywordLocal + `"`
+	case itemMatchKeyword:
+		return `".` + keywordMatch + `"`
+	case itemMarkupOpen, itemMarkupClose:
+		return "markup"
+	case itemQuotedLiteral, itemUnquotedLiteral:
+		return "literal"
+	}
+}
 
-	sb.WriteString(`want item "` + u.expected[0].String() + `"`)
+// describeItem returns the human-readable item with its value.
+func describeItem(itm item) string {
+	switch itm.typ {
+	default:
+		return itm.typ.String() + " " + strconv.Quote(itm.val)
+	case itemError:
+		if itm.err != nil {
+			return itm.err.Error()
+		}
 
-	for _, typ := range u.expected[1:] {
-		sb.WriteString(`, "` + typ.String() + `"`)
+		return itm.val
+	case itemEOF, itemExpressionOpen, itemExpressionClose, itemQuotedPatternOpen, itemQuotedPatternClose,
+		itemOperator, itemCatchAllKey, itemInputKeyword, itemLocalKeyword, itemMatchKeyword:
+		return describeItemType(itm.typ)
 	}
+}
 
-	sb.WriteString(`, got ` + u.actual.String())
+// joinOr joins the items, e.g. "a, b or c".
+func joinOr(items []string) string {
+	if len(items) < 2 { //nolint:mnd
+		return strings.Join(items, "")
+	}
+
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+// MarkupError is returned by [ParseWithOptions] with [RequireBalancedMarkup] when
+// the markup is unbalanced or misnested. It wraps [mf2.ErrSyntax].
+type MarkupError struct {
+	Reason     string     // why the markup is unbalanced
+	Identifier Identifier // identifier of the offending markup
+	Position   Position   // position of the markup in the input
+}
 
-	return sb.String()
+func (m MarkupError) Error() string {
+	return fmt.Sprintf(`unbalanced markup "%s" at %s: %s`, m.Identifier, m.Position, m.Reason)
 }
 
-func unexpectedErr(actual item, expected ...itemType) error {
-	return fmt.Errorf("%w: %w", mf2.ErrSyntax, UnexpectedTokenError{
-		actual:   actual,
-		expected: expected,
-	})
+func (m MarkupError) Unwrap() error {
+	return mf2.ErrSyntax
 }
 
 func keyString(key VariantKey) string {
@@ -1,9 +1,13 @@
 package parse
 
 import (
+	"errors"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+
+	"go.expect.digital/mf2"
 )
 
 func TestParseSimpleMessage(t *testing.T) {
@@ -630,11 +634,11 @@ func TestParseErrors(t *testing.T) {
 	}{
 		{
 			in:      ".local $foo={|foo| :x} .local $bar ={|bar| :x} .match $foo $bar ** {{foo}}",
-			wantErr: "parse MF2: complex message: matcher: variant keys: syntax error: missing space between keys * and *",
+			wantErr: "parse MF2: complex message: matcher: variant keys: syntax error at 1:66: missing space between keys * and *",
 		},
 		{
 			in:      ".local $foo= {|foo| :x} .local $bar = {|bar| :x} .match $foo $bar *1 {{foo}}",
-			wantErr: "parse MF2: complex message: matcher: variant keys: syntax error: missing space between keys * and 1",
+			wantErr: "parse MF2: complex message: matcher: variant keys: syntax error at 1:68: missing space between keys * and 1",
 		},
 		{
 			in:      ".input {$foo} .input {$foo} {{ }}",
@@ -648,6 +652,10 @@ func TestParseErrors(t *testing.T) {
 			in:      "Hello, { :number style=decimal style=percent }!",
 			wantErr: "parse MF2: simple message: pattern: expression: function: data model error: duplicate option name",
 		},
+		{
+			in:      ".local $x = {1 :number} .match $x\u061c1 {{one}} * {{other}}",
+			wantErr: `parse MF2: complex message: matcher: missing whitespace before selector: syntax error at 1:35: want whitespace, got unquoted literal "1"`, //nolint:lll
+		},
 	} {
 		t.Run(test.in, func(t *testing.T) {
 			t.Parallel()
@@ -660,6 +668,302 @@ func TestParseErrors(t *testing.T) {
 	}
 }
 
+func TestParseSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		in      string
+		want    SyntaxError
+		wantErr string
+	}{
+		{
+			in: "Hello, {$name!",
+			want: SyntaxError{
+				Description: `bad character "!" in expression`,
+				Excerpt:     "Hello, {$name!\n             ^",
+				Position:    Position{Offset: 13, Line: 1, Column: 14},
+			},
+			wantErr: `parse MF2: syntax error at 1:14: bad character "!" in expression`,
+		},
+		{
+			in: ".local $x = {1}\n.match $x\n\tone {{one}}\n\t* {{other}",
+			want: SyntaxError{
+				Description: "unescaped } in pattern",
+				Excerpt:     "\t* {{other}\n\t         ^",
+				Position:    Position{Offset: 49, Line: 4, Column: 11},
+			},
+			wantErr: "parse MF2: syntax error at 4:11: unescaped } in pattern",
+		},
+		{
+			in: "{$x :number style=}",
+			want: SyntaxError{
+				Found:    `"}"`,
+				Excerpt:  "{$x :number style=}\n                  ^",
+				Expected: []string{"variable", "literal"},
+				Position: Position{Offset: 18, Line: 1, Column: 19},
+			},
+			wantErr: `parse MF2: simple message: pattern: expression: function: option: syntax error at 1:19: want variable or literal, got "}"`, //nolint:lll
+		},
+		{
+			in: "{{ā}} x",
+			want: SyntaxError{
+				Found:    `unquoted literal "x"`,
+				Excerpt:  "{{ā}} x\n      ^",
+				Expected: []string{"end of input"},
+				Position: Position{Offset: 7, Line: 1, Column: 7},
+			},
+			wantErr: `parse MF2: syntax error at 1:7: want end of input, got unquoted literal "x"`,
+		},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(test.in)
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("\nwant '%s'\ngot  '%s'", test.wantErr, err)
+			}
+
+			if !errors.Is(err, mf2.ErrSyntax) {
+				t.Errorf("want error '%v', got '%v'", mf2.ErrSyntax, err)
+			}
+
+			var got SyntaxError
+			if !errors.As(err, &got) {
+				t.Fatalf("want SyntaxError, got %T", err)
+			}
+
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("\nwant %#v\ngot  %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRecover(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		in              string
+		wantAST         string
+		wantDiagnostics []string
+	}{
+		{
+			in: `.input {$n :number}
+.input {$n}
+.local $x = {$y :number style=}
+.match $n
+1 {{one {$n :number minimumFractionDigits=}}}
+1 {{duplicate}}
+one two {{mismatch}}
+`,
+			wantAST: ".input { $n :number }\n.match $n\n1 {{one }}",
+			wantDiagnostics: []string{
+				"data model error at 2:9: duplicate declaration: $n",
+				`syntax error at 3:31: want variable or literal, got "}"`,
+				`syntax error at 5:43: want variable or literal, got "}"`,
+				"data model error at 6:3: duplicate variant",
+				"data model error at 7:9: variant key mismatch: 1 selectors and 2 keys",
+				"data model error at 7:21: missing fallback variant",
+			},
+		},
+		{
+			in:      "Hello, {$name!} {#b}{/i} {:}",
+			wantAST: "Hello,  { #b }{ /i } ",
+			wantDiagnostics: []string{
+				`syntax error at 1:14: bad character "!" in expression`,
+				`syntax error at 1:21: unbalanced markup "i": misnested, open markup "b" at 1:17 is not closed`,
+				`syntax error at 1:28: bad function identifier "}"`,
+				`syntax error at 1:17: unbalanced markup "b": not closed`,
+			},
+		},
+		{
+			in:      ".local $x = {1} {{ {$x} }} .local",
+			wantAST: ".local $x = { 1 }\n{{ { $x } }}",
+			wantDiagnostics: []string{
+				`syntax error at 1:28: want end of input, got ".local"`,
+			},
+		},
+		{
+			in:      "Hello, {$name}!",
+			wantAST: "Hello, { $name }!",
+		},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := ParseWithOptions(test.in, Recover(), RequireBalancedMarkup())
+
+			if got := tree.String(); got != test.wantAST {
+				t.Errorf("\nwant AST '%s'\ngot      '%s'", test.wantAST, got)
+			}
+
+			if len(test.wantDiagnostics) == 0 {
+				if err != nil {
+					t.Error(err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, mf2.ErrSyntax) {
+				t.Errorf("want error '%v', got '%v'", mf2.ErrSyntax, err)
+			}
+
+			var diagnostics Diagnostics
+			if !errors.As(err, &diagnostics) {
+				t.Fatalf("want Diagnostics, got %T", err)
+			}
+
+			got := make([]string, len(diagnostics))
+			for i, diagnostic := range diagnostics {
+				if _, ok := diagnostic.(SyntaxError); !ok {
+					t.Errorf("want SyntaxError, got %T", diagnostic)
+				}
+
+				got[i] = diagnostic.Error()
+			}
+
+			if !reflect.DeepEqual(test.wantDiagnostics, got) {
+				t.Errorf("\nwant %q\ngot  %q", test.wantDiagnostics, got)
+			}
+		})
+	}
+}
+
+func TestParseRecoverWrappedErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseWithOptions(".input {$n}\n.input {$n}\n{{{#b}}}", Recover(), RequireBalancedMarkup())
+
+	var diagnostics Diagnostics
+	if !errors.As(err, &diagnostics) || len(diagnostics) != 2 {
+		t.Fatalf("want 2 diagnostics, got '%v'", err)
+	}
+
+	if !errors.Is(diagnostics[0], mf2.ErrDuplicateDeclaration) || errors.Is(diagnostics[0], mf2.ErrSyntax) {
+		t.Errorf("want error '%v', got '%v'", mf2.ErrDuplicateDeclaration, diagnostics[0])
+	}
+
+	var markupErr MarkupError
+	if !errors.As(diagnostics[1], &markupErr) || !errors.Is(diagnostics[1], mf2.ErrSyntax) {
+		t.Errorf("want MarkupError, got '%v'", diagnostics[1])
+	}
+
+	if want := "syntax error at 3:3: unbalanced markup \"b\": not closed"; diagnostics[1].Error() != want {
+		t.Errorf("want '%s', got '%s'", want, diagnostics[1])
+	}
+}
+
+func TestParseBalancedMarkup(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		in      string
+		wantErr string
+	}{
+		{in: "{#b}bold {#i}italic{/i}{/b} {#br/}"},
+		{in: ".input {$x :number} .match $x 1 {{{#b}one{/b}}} * {{{#b}other{/b}}}"},
+		{
+			in:      "{/b}text{#b}",
+			wantErr: `parse MF2: simple message: pattern: unbalanced markup "b" at 1:1: closed without being opened`,
+		},
+		{
+			in:      "{#i}{/b}",
+			wantErr: `parse MF2: simple message: pattern: unbalanced markup "b" at 1:5: misnested, open markup "i" at 1:1 is not closed`, //nolint:lll
+		},
+		{
+			in:      "{#b}bold",
+			wantErr: `parse MF2: simple message: pattern: unbalanced markup "b" at 1:1: not closed`,
+		},
+		{
+			in:      ".local $x = {1}\n{{\nline {#html:b}bold}}",
+			wantErr: `parse MF2: complex message: pattern: unbalanced markup "html:b" at 3:6: not closed`,
+		},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseWithOptions(test.in, RequireBalancedMarkup())
+
+			if test.wantErr == "" {
+				if err != nil {
+					t.Error(err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("\nwant '%s'\ngot  '%s'", test.wantErr, err)
+			}
+
+			var markupErr MarkupError
+			if !errors.As(err, &markupErr) {
+				t.Errorf("want MarkupError, got %T", err)
+			}
+
+			if !errors.Is(err, mf2.ErrSyntax) {
+				t.Errorf("want error '%s', got '%s'", mf2.ErrSyntax, err)
+			}
+		})
+	}
+
+	// markup is not validated by default
+	if _, err := Parse("{/b}text{#b}"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	t.Parallel()
+
+	input := ".input {$n :number}\n.local $x = {$n :integer @a=b}\n.match $x\n1 {{one {#b u:id=x}{$x}{/b}}}\n* {{other}}"
+
+	tree, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := tree.Message.(ComplexMessage)                //nolint:forcetypeassert
+	inputDecl := message.Declarations[0].(InputDeclaration) //nolint:forcetypeassert
+	local := message.Declarations[1].(LocalDeclaration)     //nolint:forcetypeassert
+	function := local.Expression.Annotation.(Function)      //nolint:forcetypeassert
+	matcher := message.ComplexBody.(Matcher)                //nolint:forcetypeassert
+	markup := matcher.Variants[0].QuotedPattern[1].(Markup) //nolint:forcetypeassert
+
+	for _, test := range []struct {
+		want string
+		span Span
+		line int
+		col  int
+	}{
+		{span: message.Span, want: input, line: 1, col: 1},
+		{span: inputDecl.Span, want: ".input {$n :number}", line: 1, col: 1},
+		{span: local.Span, want: ".local $x = {$n :integer @a=b}", line: 2, col: 1},
+		{span: local.Expression.Span, want: "{$n :integer @a=b}", line: 2, col: 13},
+		{span: function.Span, want: ":integer", line: 2, col: 17},
+		{span: function.Identifier.Span, want: "integer", line: 2, col: 18},
+		{span: local.Expression.Attributes[0].Span, want: "@a=b", line: 2, col: 26},
+		{span: matcher.Span, want: input[strings.Index(input, ".match"):], line: 3, col: 1},
+		{span: matcher.Variants[0].Span, want: "1 {{one {#b u:id=x}{$x}{/b}}}", line: 4, col: 1},
+		{span: markup.Span, want: "{#b u:id=x}", line: 4, col: 9},
+		{span: markup.Options[0].Span, want: "u:id=x", line: 4, col: 13},
+		{span: matcher.Variants[1].Keys[0].(CatchAllKey).Span, want: "*", line: 5, col: 1}, //nolint:forcetypeassert
+	} {
+		t.Run(test.want, func(t *testing.T) {
+			t.Parallel()
+
+			if got := input[test.span.Start.Offset:test.span.End.Offset]; got != test.want {
+				t.Errorf("want '%s', got '%s'", test.want, got)
+			}
+
+			if test.span.Start.Line != test.line || test.span.Start.Column != test.col {
+				t.Errorf("want start %d:%d, got %s", test.line, test.col, test.span.Start)
+			}
+		})
+	}
+}
+
 // helpers
 
 // requireEqualMF2String compares two strings, but ignores whitespace, tabs, and newlines.
@@ -680,6 +984,8 @@ func requireEqualMF2String(t *testing.T, want, got string) {
 func BenchmarkParse(b *testing.B) {
 	var tree AST
 
+	b.ReportAllocs()
+
 	for range b.N {
 		tree, _ = Parse(`
 .input {$foo :number @attribute=value}
@@ -0,0 +1,347 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quoting defines how [Printer] quotes the literals.
+type Quoting int
+
+const (
+	// QuoteAsNeeded quotes only the literals that cannot be written unquoted, e.g. |hello world|.
+	QuoteAsNeeded Quoting = iota
+	// QuotePreserve keeps the quoting of the literals in the AST.
+	QuotePreserve
+	// QuoteAlways quotes all literals.
+	QuoteAlways
+)
+
+/*
+Printer formats the AST nodes as MF2. The zero Printer prints the compact form:
+
+	.input {$count :number}
+	.match $count
+	one {{You have {$count} notification.}}
+	* {{You have {$count} notifications.}}
+
+Unlike [AST.String], the declarations, the selectors and each variant are printed on a separate line.
+*/
+type Printer struct {
+	// Indent is the indentation of the variants, e.g. "  ".
+	Indent string
+	// BraceSpaces adds the spaces inside the expression and markup braces, e.g. "{ $count :number }".
+	BraceSpaces bool
+	// AlignKeys pads the variant keys to align the keys and the patterns into columns.
+	AlignKeys bool
+	// Quoting defines how the literals are quoted.
+	Quoting Quoting
+}
+
+// canonical is the printer of the canonical form, see [Format].
+var canonical = Printer{AlignKeys: true}
+
+/*
+Format parses the input and returns the canonical form of the message. The canonical form
+is printed without the spaces inside the braces, with the variant keys aligned into columns,
+and the literals quoted only when needed.
+
+Example:
+
+	s, _ := parse.Format(".input { $count :number minimumFractionDigits = |0| } .match $count one {{{ $count } item}} * {{{ $count } items}}")
+
+	fmt.Println(s)
+	// .input {$count :number minimumFractionDigits=0}
+	// .match $count
+	// one {{{$count} item}}
+	// *   {{{$count} items}}
+*/
+func Format(input string) (string, error) {
+	ast, err := Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("format: %w", err)
+	}
+
+	return canonical.Sprint(ast.Message), nil
+}
+
+// Sprint returns the formatted node.
+func (p Printer) Sprint(node Node) string {
+	pp := printer{Printer: p}
+	pp.node(node)
+
+	return pp.String()
+}
+
+// Fprint writes the formatted node to w.
+func (p Printer) Fprint(w io.Writer, node Node) error {
+	if _, err := io.WriteString(w, p.Sprint(node)); err != nil {
+		return fmt.Errorf("print MF2: %w", err)
+	}
+
+	return nil
+}
+
+// printer is the state of the [Printer].
+type printer struct {
+	strings.Builder
+	Printer
+}
+
+//nolint:cyclop
+func (p *printer) node(node Node) {
+	switch n := node.(type) {
+	default:
+		p.WriteString(node.String())
+	case nil:
+	case SimpleMessage:
+		p.pattern(n)
+	case ComplexMessage:
+		p.complexMessage(n)
+	case QuotedPattern:
+		p.quotedPattern(n)
+	case Matcher:
+		p.matcher(n)
+	case Variant:
+		p.variant(n, nil)
+	case InputDeclaration:
+		p.WriteString(input + " ")
+		p.expression(Expression(n))
+	case LocalDeclaration:
+		p.WriteString(local + " " + n.Variable.String() + " = ")
+		p.expression(n.Expression)
+	case Expression:
+		p.expression(n)
+	case Markup:
+		p.markup(n)
+	case Function:
+		p.function(n)
+	case Option:
+		p.option(n.Identifier, n.Value)
+	case Attribute:
+		p.attribute(n)
+	case Literal:
+		p.WriteString(p.literal(n))
+	}
+}
+
+func (p *printer) complexMessage(message ComplexMessage) {
+	for _, declaration := range message.Declarations {
+		p.node(declaration)
+		p.WriteString("\n")
+	}
+
+	p.node(message.ComplexBody)
+}
+
+func (p *printer) pattern(pattern []PatternPart) {
+	for _, part := range pattern {
+		if text, ok := part.(Text); ok {
+			p.WriteString(text.String())
+			continue
+		}
+
+		p.node(part)
+	}
+}
+
+func (p *printer) quotedPattern(pattern QuotedPattern) {
+	p.WriteString("{{")
+	p.pattern(pattern)
+	p.WriteString("}}")
+}
+
+func (p *printer) matcher(matcher Matcher) {
+	p.WriteString(match)
+
+	for _, selector := range matcher.Selectors {
+		p.WriteString(" " + selector.String())
+	}
+
+	var widths []int
+
+	if p.AlignKeys {
+		widths = keyWidths(matcher.Variants, p.key)
+	}
+
+	for _, variant := range matcher.Variants {
+		p.WriteString("\n" + p.Indent)
+		p.variant(variant, widths)
+	}
+}
+
+// keyWidths returns the widths of the key columns in characters.
+func keyWidths(variants []Variant, key func(VariantKey) string) []int {
+	var widths []int
+
+	for _, variant := range variants {
+		for i, k := range variant.Keys {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], utf8.RuneCountInString(key(k)))
+		}
+	}
+
+	return widths
+}
+
+// variant prints the variant with the keys padded to the widths, if any.
+func (p *printer) variant(variant Variant, widths []int) {
+	for i, key := range variant.Keys {
+		s := p.key(key)
+		p.WriteString(s + " ")
+
+		if i < len(widths) {
+			p.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s)))
+		}
+	}
+
+	p.quotedPattern(variant.QuotedPattern)
+}
+
+func (p *printer) key(key VariantKey) string {
+	if literal, ok := key.(Literal); ok {
+		return p.literal(literal)
+	}
+
+	return key.String()
+}
+
+func (p *printer) literal(literal Literal) string {
+	var (
+		value  string
+		quoted bool
+	)
+
+	switch l := literal.(type) {
+	default:
+		return literal.String()
+	case QuotedLiteral:
+		value, quoted = string(l), true
+	case NameLiteral:
+		value = string(l)
+	}
+
+	switch p.Quoting {
+	case QuoteAsNeeded:
+		quoted = !isUnquotedLiteral(value)
+	case QuotePreserve:
+	case QuoteAlways:
+		quoted = true
+	}
+
+	if quoted {
+		return QuotedLiteral(value).String()
+	}
+
+	return value
+}
+
+func (p *printer) value(value Value) string {
+	if literal, ok := value.(Literal); ok {
+		return p.literal(literal)
+	}
+
+	return value.String()
+}
+
+func (p *printer) openBrace() {
+	p.WriteString("{")
+
+	if p.BraceSpaces {
+		p.WriteString(" ")
+	}
+}
+
+func (p *printer) closeBrace() {
+	if p.BraceSpaces {
+		p.WriteString(" ")
+	}
+
+	p.WriteString("}")
+}
+
+func (p *printer) expression(expr Expression) {
+	p.openBrace()
+
+	sep := ""
+
+	if expr.Operand != nil {
+		p.WriteString(p.value(expr.Operand))
+
+		sep = " "
+	}
+
+	if expr.Annotation != nil {
+		p.WriteString(sep)
+		p.node(expr.Annotation)
+
+		sep = " "
+	}
+
+	for _, attribute := range expr.Attributes {
+		p.WriteString(sep)
+		p.attribute(attribute)
+
+		sep = " "
+	}
+
+	p.closeBrace()
+}
+
+func (p *printer) function(function Function) {
+	p.WriteString(":" + function.Identifier.String())
+
+	for _, option := range function.Options {
+		p.WriteString(" ")
+		p.option(option.Identifier, option.Value)
+	}
+}
+
+func (p *printer) option(identifier Identifier, value Value) {
+	p.WriteString(identifier.String() + "=" + p.value(value))
+}
+
+func (p *printer) attribute(attribute Attribute) {
+	p.WriteString("@" + attribute.Identifier.String())
+
+	if attribute.Value != nil {
+		p.WriteString("=" + p.value(attribute.Value))
+	}
+}
+
+func (p *printer) markup(markup Markup) {
+	p.openBrace()
+
+	switch markup.Typ {
+	case Unspecified, Open, SelfClose:
+		p.WriteString("#" + markup.Identifier.String())
+	case Close:
+		p.WriteString("/" + markup.Identifier.String())
+	}
+
+	for _, option := range markup.Options {
+		p.WriteString(" ")
+		p.option(option.Identifier, option.Value)
+	}
+
+	for _, attribute := range markup.Attributes {
+		p.WriteString(" ")
+		p.attribute(attribute)
+	}
+
+	if markup.Typ != SelfClose {
+		p.closeBrace()
+		return
+	}
+
+	if p.BraceSpaces {
+		p.WriteString(" ")
+	}
+
+	p.WriteString("/}")
+}
@@ -0,0 +1,115 @@
+package parse
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  "",
+		},
+		{
+			name:  "simple message",
+			input: "Hello, { $name :string  @translate = no }! \\{escaped\\}",
+			want:  "Hello, {$name :string @translate=no}! \\{escaped\\}",
+		},
+		{
+			name:  "declarations",
+			input: ".input { $n :number minimumFractionDigits = |2| } .local $x = { |a b| }  {{{ $x }}}",
+			want:  ".input {$n :number minimumFractionDigits=2}\n.local $x = {|a b|}\n{{{$x}}}",
+		},
+		{
+			name:  "aligned keys",
+			input: ".input {$a :number} .input {$b :string} .match $a $b 1 x {{one}} other |long key| {{other}} * * {{*}}",
+			want: ".input {$a :number}\n.input {$b :string}\n.match $a $b\n" +
+				"1     x          {{one}}\n" +
+				"other |long key| {{other}}\n" +
+				"*     *          {{*}}",
+		},
+		{
+			name:  "markup",
+			input: "{ #b class = |big| @a }bold{ /b @c }{ #br /}",
+			want:  "{#b class=big @a}bold{/b @c}{#br/}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Format(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != got {
+				t.Errorf("want\n%s\ngot\n%s", test.want, got)
+			}
+
+			// canonical form is stable
+			again, err := Format(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != again {
+				t.Errorf("want stable\n%s\ngot\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	t.Parallel()
+
+	input := ".input {$n :number} .match $n one {{{#b}one{/b} {|x|}}} * {{{name}}}"
+
+	tests := []struct {
+		name    string
+		printer Printer
+		want    string
+	}{
+		{
+			name:    "zero",
+			printer: Printer{},
+			want:    ".input {$n :number}\n.match $n\none {{{#b}one{/b} {x}}}\n* {{{name}}}",
+		},
+		{
+			name:    "indent and brace spaces",
+			printer: Printer{Indent: "  ", BraceSpaces: true, AlignKeys: true},
+			want:    ".input { $n :number }\n.match $n\n  one {{{ #b }one{ /b } { x }}}\n  *   {{{ name }}}",
+		},
+		{
+			name:    "preserve quoting",
+			printer: Printer{Quoting: QuotePreserve},
+			want:    ".input {$n :number}\n.match $n\none {{{#b}one{/b} {|x|}}}\n* {{{name}}}",
+		},
+		{
+			name:    "quote always",
+			printer: Printer{Quoting: QuoteAlways},
+			want:    ".input {$n :number}\n.match $n\n|one| {{{#b}one{/b} {|x|}}}\n* {{{|name|}}}",
+		},
+	}
+
+	ast, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.printer.Sprint(ast.Message); test.want != got {
+				t.Errorf("want\n%s\ngot\n%s", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package parse
+
+// Spans are the spans of the nodes without the Span field - the simple message, quoted patterns,
+// text, literals and variables - in the order the nodes are visited by [Walk]. See [RecordSpans].
+//
+// The spans are matched with the nodes by their order only. They are valid for the unmodified
+// parsed message, the spans of a message changed by [Rewrite] or by hand are not the spans of its nodes.
+type Spans []Span
+
+// RecordSpans makes [ParseWithOptions] record the spans of the nodes without the Span field into spans.
+// Use [Spans.Inspect] to get the span of every node of the parsed message.
+//
+// Example:
+//
+//	var spans parse.Spans
+//
+//	tree, _ := parse.ParseWithOptions(".input {$n :number}\n{{{$n}}}", parse.RecordSpans(&spans))
+//
+//	spans.Inspect(tree.Message, func(node parse.Node, span parse.Span) bool {
+//		if v, ok := node.(parse.Variable); ok {
+//			fmt.Println(v, span.Start) // $n 1:9, $n 2:4
+//		}
+//
+//		return true
+//	})
+func RecordSpans(spans *Spans) ParseOption {
+	return func(p *parser) {
+		p.spans = spans
+	}
+}
+
+// Inspect traverses the message like [Inspect] and calls f with each node and its span - the Span field
+// of the struct nodes or the recorded span of the other nodes. The message must be the parsed message
+// the spans are recorded for, unmodified - after adding, deleting or reordering the nodes
+// the remaining spans are reported for the wrong nodes.
+func (s Spans) Inspect(message Message, f func(node Node, span Span) bool) {
+	if message == nil {
+		return
+	}
+
+	Walk(&spanInspector{spans: s, f: f}, message)
+}
+
+type spanInspector struct {
+	f     func(node Node, span Span) bool
+	spans Spans
+	i     int // the next recorded span
+}
+
+func (v *spanInspector) Visit(node Node) Visitor {
+	if node == nil {
+		v.f(nil, Span{})
+		return nil
+	}
+
+	span := nodeSpan(node)
+
+	if isValueNode(node) {
+		if v.i < len(v.spans) {
+			span = v.spans[v.i]
+		}
+
+		v.i++
+	}
+
+	if v.f(node, span) {
+		return v
+	}
+
+	// the children are not inspected, but their recorded spans are skipped
+	Walk(spanSkipper{v}, node)
+
+	return nil
+}
+
+// spanSkipper skips the recorded spans of the children of the node.
+type spanSkipper struct {
+	v *spanInspector
+}
+
+func (s spanSkipper) Visit(node Node) Visitor {
+	if isValueNode(node) {
+		s.v.i++
+	}
+
+	return s
+}
+
+// isValueNode returns true if the node has no Span field.
+func isValueNode(node Node) bool {
+	switch node.(type) {
+	case SimpleMessage, QuotedPattern, Text, QuotedLiteral, NameLiteral, Variable:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordSpans returns the spans of the nodes without the Span field. The nodes are matched
+// with the lexed items in the source order, the items of the nodes omitted by [Recover] are skipped.
+func (p *parser) recordSpans(message Message) Spans {
+	var (
+		spans Spans
+		i     int // the next item
+	)
+
+	// next returns the span of the next item of the type, and moves past it.
+	next := func(typ itemType) Span {
+		for ; i < len(p.items); i++ {
+			if itm := p.items[i]; itm.typ == typ {
+				i++
+				return p.span(itm.pos, itm.end)
+			}
+		}
+
+		return Span{}
+	}
+
+	Inspect(message, func(node Node) bool {
+		switch n := node.(type) {
+		case nil:
+		case SimpleMessage:
+			spans = append(spans, p.span(0, len(p.input)))
+		case QuotedPattern:
+			start := next(itemQuotedPatternOpen)
+			open := i
+
+			// the quoted patterns are not nested, the parts are matched after the open item
+			end := next(itemQuotedPatternClose)
+			i = open
+
+			spans = append(spans, Span{Start: start.Start, End: end.End})
+		case Text:
+			spans = append(spans, next(itemText))
+		case QuotedLiteral:
+			spans = append(spans, next(itemQuotedLiteral))
+		case NameLiteral:
+			spans = append(spans, next(itemUnquotedLiteral))
+		case Variable:
+			spans = append(spans, next(itemVariable))
+		default:
+			// skip the items of the omitted nodes before the node
+			for start := nodeSpan(n).Start.Offset; i < len(p.items) && p.items[i].pos < start; {
+				i++
+			}
+		}
+
+		return true
+	})
+
+	return spans
+}
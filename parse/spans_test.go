@@ -0,0 +1,154 @@
+package parse
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRecordSpans(t *testing.T) {
+	t.Parallel()
+
+	input := ".input {$count :integer}\n.local $name = {|Jane Doe| :string}\n.match $count\n" +
+		"0 {{No items for {$name}}}\none {{One item {$count :number minimumFractionDigits=two}}}\n* {{{$count} items}}"
+
+	// the nodes without the Span field, their source text and start position
+	all := []string{
+		"Variable $count 1:9",
+		"Variable $name 2:8",
+		"QuotedLiteral |Jane Doe| 2:17",
+		"Variable $count 3:8",
+		"NameLiteral 0 4:1",
+		"QuotedPattern {{No items for {$name}}} 4:3",
+		"Text No items for  4:5",
+		"Variable $name 4:19",
+		"NameLiteral one 5:1",
+		"QuotedPattern {{One item {$count :number minimumFractionDigits=two}}} 5:5",
+		"Text One item  5:7",
+		"Variable $count 5:17",
+		"NameLiteral two 5:54",
+		"QuotedPattern {{{$count} items}} 6:3",
+		"Variable $count 6:6",
+		"Text  items 6:13",
+	}
+
+	for _, test := range []struct {
+		// skip is the node type of the skipped children
+		skip string
+		want []string
+	}{
+		{want: all},
+		{skip: "Variant", want: all[:4]},
+		{skip: "Expression", want: slices.Concat(all[:2], all[3:7], all[8:11], all[13:14], all[15:])},
+	} {
+		t.Run("skip "+test.skip, func(t *testing.T) {
+			t.Parallel()
+
+			var spans Spans
+
+			tree, err := ParseWithOptions(input, RecordSpans(&spans))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			spans.Inspect(tree.Message, func(node Node, span Span) bool {
+				if node == nil {
+					return true
+				}
+
+				typ := fmt.Sprintf("%T", node)[len("parse."):]
+
+				if isValueNode(node) {
+					got = append(got, fmt.Sprintf("%s %s %s", typ, input[span.Start.Offset:span.End.Offset], span.Start))
+				}
+
+				return typ != test.skip
+			})
+
+			if !slices.Equal(test.want, got) {
+				t.Errorf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordSpans_SimpleMessage(t *testing.T) {
+	t.Parallel()
+
+	input := "Hello,\n{$name} and {|friends|}!"
+
+	var spans Spans
+
+	tree, err := ParseWithOptions(input, RecordSpans(&spans))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Hello,\n{$name} and {|friends|}! 1:1",
+		"Hello,\n 1:1",
+		"{$name} 2:1",
+		"$name 2:2",
+		" and  2:8",
+		"{|friends|} 2:13",
+		"|friends| 2:14",
+		"! 2:24",
+	}
+
+	var got []string
+
+	spans.Inspect(tree.Message, func(node Node, span Span) bool {
+		if node != nil {
+			got = append(got, fmt.Sprintf("%s %s", input[span.Start.Offset:span.End.Offset], span.Start))
+		}
+
+		return true
+	})
+
+	if !slices.Equal(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// TestRecordSpans_Rewrite documents that the recorded spans are valid only for the unmodified message.
+func TestRecordSpans_Rewrite(t *testing.T) {
+	t.Parallel()
+
+	input := "{$a} and {$b}"
+
+	var spans Spans
+
+	tree, err := ParseWithOptions(input, RecordSpans(&spans))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delete the first expression
+	message := Rewrite(tree.Message, func(c *Cursor) bool {
+		if expr, ok := c.Node().(Expression); ok && expr.Operand == Variable("a") {
+			c.Delete()
+		}
+
+		return true
+	}, nil)
+
+	var got []string
+
+	spans.Inspect(message.(Message), func(node Node, span Span) bool { //nolint:forcetypeassert
+		switch node.(type) {
+		case Text, Variable:
+			got = append(got, fmt.Sprintf("%q %q", node, input[span.Start.Offset:span.End.Offset]))
+		}
+
+		return true
+	})
+
+	// the spans are shifted, the span of the deleted $a is reported for the text
+	want := []string{`" and " "$a"`, `"$b" " and "`}
+
+	if !slices.Equal(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,364 @@
+package parse
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Visitor's Visit method is invoked for each node encountered by [Walk].
+// If the result visitor w is not nil, [Walk] visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling v.Visit(node); node must not be nil.
+// If the visitor w returned by v.Visit(node) is not nil, Walk is invoked recursively with visitor w
+// for each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// The children are visited in the source order:
+//
+//   - [SimpleMessage], [QuotedPattern] - pattern parts
+//   - [ComplexMessage] - declarations, complex body
+//   - [InputDeclaration], [Expression] - operand, annotation, attributes
+//   - [LocalDeclaration] - variable, expression
+//   - [Function] - identifier, options
+//   - [Option], [Attribute] - identifier, value
+//   - [Markup] - identifier, options, attributes
+//   - [Matcher] - selectors, variants
+//   - [Variant] - keys, quoted pattern
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case SimpleMessage:
+		walkList(v, n)
+	case QuotedPattern:
+		walkList(v, n)
+	case ComplexMessage:
+		walkList(v, n.Declarations)
+
+		if n.ComplexBody != nil {
+			Walk(v, n.ComplexBody)
+		}
+	case InputDeclaration:
+		walkExpression(v, Expression(n))
+	case Expression:
+		walkExpression(v, n)
+	case LocalDeclaration:
+		Walk(v, n.Variable)
+		Walk(v, n.Expression)
+	case Function:
+		Walk(v, n.Identifier)
+		walkList(v, n.Options)
+	case Option:
+		Walk(v, n.Identifier)
+
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case Attribute:
+		Walk(v, n.Identifier)
+
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case Markup:
+		Walk(v, n.Identifier)
+		walkList(v, n.Options)
+		walkList(v, n.Attributes)
+	case Matcher:
+		walkList(v, n.Selectors)
+		walkList(v, n.Variants)
+	case Variant:
+		walkList(v, n.Keys)
+		Walk(v, n.QuotedPattern)
+	}
+
+	v.Visit(nil)
+}
+
+func walkExpression(v Visitor, expr Expression) {
+	if expr.Operand != nil {
+		Walk(v, expr.Operand)
+	}
+
+	if expr.Annotation != nil {
+		Walk(v, expr.Annotation)
+	}
+
+	walkList(v, expr.Attributes)
+}
+
+func walkList[T Node](v Visitor, list []T) {
+	for _, node := range list {
+		Walk(v, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling f(node); node must not be nil.
+// If f returns true, Inspect invokes f recursively for each of the non-nil children of node,
+// followed by a call of f(nil).
+//
+// Example:
+//
+//	// collect all variables
+//	var variables []parse.Variable
+//
+//	parse.Inspect(tree.Message, func(node parse.Node) bool {
+//		if v, ok := node.(parse.Variable); ok {
+//			variables = append(variables, v)
+//		}
+//
+//		return true
+//	})
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// ApplyFunc is invoked by [Rewrite] for each node, see [Rewrite].
+type ApplyFunc func(*Cursor) bool
+
+// Cursor describes a node encountered during [Rewrite].
+type Cursor struct {
+	node      Node
+	parent    Node
+	deletable bool
+	deleted   bool
+	replaced  bool
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current node, before its children are rewritten.
+// It returns nil for the root node.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Replace replaces the current node with n. The replacement node is not traversed by [Rewrite].
+// The type of n must be allowed at the position of the current node, otherwise [Rewrite] panics.
+func (c *Cursor) Replace(n Node) {
+	c.node = n
+	c.deleted = false
+	c.replaced = true
+}
+
+// Delete deletes the current node. Only the root node and the elements of the lists allowing removal -
+// pattern parts, declarations, options, attributes and variants - can be deleted, otherwise Delete panics.
+// The required children, e.g. the option value, the selectors and the variant keys, cannot be deleted.
+func (c *Cursor) Delete() {
+	if !c.deletable {
+		panic(fmt.Sprintf("parse: cannot delete %T from %T", c.node, c.parent))
+	}
+
+	c.deleted = true
+}
+
+/*
+Rewrite traverses an AST recursively, starting with node, and returns the rewritten AST.
+The AST nodes are values, the original AST is not modified.
+
+If pre is not nil, it is called for each node before the node's children are traversed (pre-order).
+If pre returns false, no children are traversed, and post is not called for that node.
+
+If post is not nil, and a prior call of pre did not return false, post is called for each node
+after its children are traversed (post-order). If post returns false, the traversal is terminated
+and Rewrite returns immediately.
+
+The node is replaced or deleted with [Cursor.Replace] and [Cursor.Delete]. Rewrite returns nil if
+the root node is deleted.
+
+Example:
+
+	// strip markup
+	message := parse.Rewrite(tree.Message, func(c *parse.Cursor) bool {
+		if _, ok := c.Node().(parse.Markup); ok {
+			c.Delete()
+		}
+
+		return true
+	}, nil)
+*/
+func Rewrite(node Node, pre, post ApplyFunc) Node {
+	a := &application{pre: pre, post: post}
+
+	result, deleted := a.apply(nil, node, true)
+	if deleted {
+		return nil
+	}
+
+	return result
+}
+
+type application struct {
+	pre, post ApplyFunc
+	aborted   bool
+}
+
+// apply rewrites the node and its children. It returns true if the node is deleted.
+func (a *application) apply(parent, node Node, deletable bool) (Node, bool) {
+	if a.aborted {
+		return node, false
+	}
+
+	cursor := Cursor{node: node, parent: parent, deletable: deletable}
+
+	if a.pre != nil && !a.pre(&cursor) {
+		return cursor.node, cursor.deleted
+	}
+
+	if cursor.deleted {
+		return nil, true
+	}
+
+	// the replacement node is not traversed
+	if cursor.replaced {
+		return cursor.node, false
+	}
+
+	cursor.node = a.applyChildren(node)
+
+	if a.post != nil && !a.post(&cursor) {
+		a.aborted = true
+	}
+
+	return cursor.node, cursor.deleted
+}
+
+// applyOne rewrites the node that cannot be deleted.
+func applyOne[T Node](a *application, parent Node, node T) T {
+	result, _ := a.apply(parent, node, false)
+
+	return assertNode[T](result, parent)
+}
+
+// applyOptional rewrites the optional node that cannot be deleted, if the node is not nil.
+func applyOptional[T Node](a *application, parent Node, node T) T {
+	if Node(node) == nil {
+		return node
+	}
+
+	return applyOne(a, parent, node)
+}
+
+// applyList rewrites the list of nodes, and removes the deleted nodes.
+func applyList[S ~[]T, T Node](a *application, parent Node, list S) S {
+	if len(list) == 0 {
+		return list
+	}
+
+	result := make(S, 0, len(list))
+
+	for _, node := range list {
+		if n, deleted := a.apply(parent, node, true); !deleted {
+			result = append(result, assertNode[T](n, parent))
+		}
+	}
+
+	return result
+}
+
+// applyEach rewrites the list of nodes that cannot be deleted.
+func applyEach[S ~[]T, T Node](a *application, parent Node, list S) S {
+	if len(list) == 0 {
+		return list
+	}
+
+	result := make(S, 0, len(list))
+
+	for _, node := range list {
+		result = append(result, applyOne(a, parent, node))
+	}
+
+	return result
+}
+
+// assertNode returns the node as type T, and panics if the node type is not allowed.
+func assertNode[T Node](node, parent Node) T {
+	v, ok := node.(T)
+	if !ok {
+		panic(fmt.Sprintf("parse: cannot use %T as %s in %T", node, reflect.TypeFor[T](), parent))
+	}
+
+	return v
+}
+
+//nolint:cyclop
+func (a *application) applyChildren(node Node) Node {
+	switch n := node.(type) {
+	default:
+		return node
+	case SimpleMessage:
+		return applyList(a, n, n)
+	case QuotedPattern:
+		return applyList(a, n, n)
+	case ComplexMessage:
+		n.Declarations = applyList(a, n, n.Declarations)
+
+		if n.ComplexBody != nil {
+			n.ComplexBody = applyOne(a, n, n.ComplexBody)
+		}
+
+		return n
+	case InputDeclaration:
+		return InputDeclaration(a.applyExpression(n, Expression(n)))
+	case Expression:
+		return a.applyExpression(n, n)
+	case LocalDeclaration:
+		n.Variable = applyOne(a, n, n.Variable)
+		n.Expression = applyOne(a, n, n.Expression)
+
+		return n
+	case Function:
+		n.Identifier = applyOne(a, n, n.Identifier)
+		n.Options = applyList(a, n, n.Options)
+
+		return n
+	case Option:
+		n.Identifier = applyOne(a, n, n.Identifier)
+		n.Value = applyOptional(a, n, n.Value)
+
+		return n
+	case Attribute:
+		n.Identifier = applyOne(a, n, n.Identifier)
+		n.Value = applyOptional(a, n, n.Value)
+
+		return n
+	case Markup:
+		n.Identifier = applyOne(a, n, n.Identifier)
+		n.Options = applyList(a, n, n.Options)
+		n.Attributes = applyList(a, n, n.Attributes)
+
+		return n
+	case Matcher:
+		n.Selectors = applyEach(a, n, n.Selectors)
+		n.Variants = applyList(a, n, n.Variants)
+
+		return n
+	case Variant:
+		n.Keys = applyEach(a, n, n.Keys)
+		n.QuotedPattern = applyOne(a, n, n.QuotedPattern)
+
+		return n
+	}
+}
+
+func (a *application) applyExpression(parent Node, expr Expression) Expression {
+	expr.Operand = applyOptional(a, parent, expr.Operand)
+	expr.Annotation = applyOptional(a, parent, expr.Annotation)
+	expr.Attributes = applyList(a, parent, expr.Attributes)
+
+	return expr
+}
@@ -0,0 +1,310 @@
+package parse
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	tree, err := Parse(".local $x = {$y :number minimumFractionDigits=2} .match $x one {{{#b}one{/b}}} * {{other}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		got   []string
+		depth int
+	)
+
+	Inspect(tree.Message, func(node Node) bool {
+		if node == nil {
+			depth--
+			return true
+		}
+
+		depth++
+
+		got = append(got, strings.TrimPrefix(fmt.Sprintf("%T", node), "parse."))
+
+		return true
+	})
+
+	if depth != 0 {
+		t.Errorf("want balanced calls, got depth %d", depth)
+	}
+
+	want := []string{
+		"ComplexMessage",
+		"LocalDeclaration", "Variable",
+		"Expression", "Variable",
+		"Function", "Identifier", "Option", "Identifier", "NameLiteral",
+		"Matcher", "Variable",
+		"Variant", "NameLiteral", "QuotedPattern", "Markup", "Identifier", "Text", "Markup", "Identifier",
+		"Variant", "CatchAllKey", "QuotedPattern", "Text",
+	}
+
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestInspectSkipChildren(t *testing.T) {
+	t.Parallel()
+
+	tree, err := Parse("Hello, { $name :string @translate=no }!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var variables []Variable
+
+	Inspect(tree.Message, func(node Node) bool {
+		if v, ok := node.(Variable); ok {
+			variables = append(variables, v)
+		}
+
+		_, ok := node.(Expression)
+
+		return !ok
+	})
+
+	if len(variables) != 0 {
+		t.Errorf("want no variables, got %v", variables)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pre, post ApplyFunc
+		name      string
+		input     string
+		want      string
+	}{
+		{
+			name:  "rename variable",
+			input: ".input {$count :number} {{You have {$count} items}}",
+			want:  ".input { $total :number }\n{{You have { $total } items}}",
+			pre: func(c *Cursor) bool {
+				if v, ok := c.Node().(Variable); ok && v == "count" {
+					c.Replace(Variable("total"))
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "strip markup",
+			input: "{#b}bold{/b} and {#i}italic{/i}",
+			want:  "bold and italic",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(Markup); ok {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "delete option",
+			input: "{$n :number u:id=x minimumFractionDigits=2}",
+			want:  "{ $n :number minimumFractionDigits = 2 }",
+			pre: func(c *Cursor) bool {
+				if o, ok := c.Node().(Option); ok && o.Identifier.Namespace == "u" {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "delete variant and declaration",
+			input: ".input {$n :number} .local $x = {1} .match $n 1 {{one}} * {{other}}",
+			want:  ".input { $n :number }\n.match $n\n* {{other}}",
+			pre: func(c *Cursor) bool {
+				switch n := c.Node().(type) {
+				case LocalDeclaration:
+					c.Delete()
+				case Variant:
+					if n.Keys[0] == NameLiteral("1") {
+						c.Delete()
+					}
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "pre false skips children",
+			input: "{$a} {|b| :f x=$c}",
+			want:  "{ $z } { |b| :f x = $c }",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(Option); ok {
+					return false
+				}
+
+				if _, ok := c.Node().(Variable); ok {
+					c.Replace(Variable("z"))
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "post false aborts",
+			input: "{$a} {$b} {$c}",
+			want:  "{ $z } { $b } { $c }",
+			post: func(c *Cursor) bool {
+				if _, ok := c.Node().(Variable); ok {
+					c.Replace(Variable("z"))
+					return false
+				}
+
+				return true
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			original := tree.String()
+
+			got := Rewrite(tree.Message, test.pre, test.post)
+
+			if test.want != got.String() {
+				t.Errorf("want '%s', got '%s'", test.want, got)
+			}
+
+			if original != tree.String() {
+				t.Errorf("want original AST '%s', got '%s'", original, tree)
+			}
+		})
+	}
+}
+
+func TestRewriteDeleteRoot(t *testing.T) {
+	t.Parallel()
+
+	got := Rewrite(SimpleMessage{Text("text")}, func(c *Cursor) bool {
+		c.Delete()
+		return true
+	}, nil)
+
+	if got != nil {
+		t.Errorf("want nil, got '%s'", got)
+	}
+}
+
+func TestRewritePanics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pre   ApplyFunc
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "replace with wrong type",
+			want: "parse: cannot use parse.Text as parse.Value in parse.Expression",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(Variable); ok {
+					c.Replace(Text("text"))
+				}
+
+				return true
+			},
+		},
+		{
+			name: "delete not deletable",
+			want: "parse: cannot delete parse.Identifier from parse.Function",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(Identifier); ok {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+		{
+			name: "delete operand",
+			want: "parse: cannot delete parse.Variable from parse.Expression",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(Variable); ok {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "delete option value",
+			input: "{$n :number minimumFractionDigits=2}",
+			want:  "parse: cannot delete parse.NameLiteral from parse.Option",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(NameLiteral); ok {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "delete selector",
+			input: ".input {$n :number} .match $n 1 {{one}} * {{other}}",
+			want:  "parse: cannot delete parse.Variable from parse.Matcher",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Parent().(Matcher); ok {
+					if _, ok := c.Node().(Variable); ok {
+						c.Delete()
+					}
+				}
+
+				return true
+			},
+		},
+		{
+			name:  "delete variant key",
+			input: ".input {$n :number} .match $n 1 {{one}} * {{other}}",
+			want:  "parse: cannot delete parse.CatchAllKey from parse.Variant",
+			pre: func(c *Cursor) bool {
+				if _, ok := c.Node().(CatchAllKey); ok {
+					c.Delete()
+				}
+
+				return true
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := Parse(cmp.Or(test.input, "{$n :number}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer func() {
+				if got := recover(); got != test.want {
+					t.Errorf("want panic '%s', got '%v'", test.want, got)
+				}
+			}()
+
+			Rewrite(tree.Message, test.pre, nil)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package template
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Run with the race detector, e.g. "go test -race ./template".
+
+// concurrencyTests are the messages calling every function of the registry.
+var concurrencyTests = map[string]string{
+	"currency": ".input {$n :currency currency=EUR} .match $n 1 {{one {$n}}} * {{other {$n}}}",
+	"date":     "{$date :date style=long timeZone=|Europe/Riga|} {$date :date}",
+	"datetime": "{$date :datetime dateStyle=medium timeStyle=short timeZone=|Europe/Riga|} {$date :datetime year=numeric}",
+	"integer":  ".input {$n :integer} .match $n one {{one {$n}}} * {{other {$n}}}",
+	"number":   ".local $x = {$n :number minimumFractionDigits=2} .match $x one {{one {$x}}} * {{other {$x}}}",
+	"offset":   ".local $x = {$n :offset subtract=1} .match $x 0 {{zero}} * {{other {$x}}}",
+	"percent":  ".local $x = {$n :percent} .match $x one {{one {$x}}} * {{other {$x}}}",
+	"string":   ".input {$s :string} .match $s hello {{hello {$s}}} * {{other {$s}}}",
+	"time":     "{$date :time style=medium timeZone=|Europe/Riga|} {$date :time}",
+}
+
+func TestConcurrentExecute(t *testing.T) {
+	t.Parallel()
+
+	for name := range NewRegistry() {
+		if _, ok := concurrencyTests[name]; !ok {
+			t.Errorf(`want concurrency test of the function "%s"`, name)
+		}
+	}
+
+	// the input is shared by all executions
+	input := map[string]any{
+		"n":    3,
+		"s":    NewResolvedValue("hello"),
+		"date": time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+
+	for name, text := range concurrencyTests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			template, err := New(WithLocale(language.English)).Parse(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the bound templates share the compiled message
+			templates := []*Template{template, template.Bind(language.Latvian), template.Bind(language.Arabic)}
+			want := make([]string, len(templates))
+
+			for i, template := range templates {
+				if want[i], err = template.Sprint(input); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var wg sync.WaitGroup
+
+			for i := range 9 {
+				wg.Go(func() {
+					template, want := templates[i%len(templates)], want[i%len(templates)]
+
+					for range 50 {
+						got, err := template.Sprint(input)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+
+						if got != want {
+							t.Errorf("want '%s', got '%s'", want, got)
+							return
+						}
+					}
+				})
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestConcurrentExecuteMutatingFunc(t *testing.T) {
+	t.Parallel()
+
+	// the custom function modifies the options it gets
+	mutate := func(input *ResolvedValue, options Options, _ language.Tag) (*ResolvedValue, error) {
+		options["calls"] = NewResolvedValue(len(options))
+		delete(options, "style")
+
+		return NewResolvedValue(input.String() + "!"), nil
+	}
+
+	template, err := New(WithFunc("mutate", mutate)).Parse("{$s :mutate style=loud} {hello :mutate style=loud}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := map[string]any{"s": "hi"}
+	want := "hi! hello!"
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Go(func() {
+			for range 50 {
+				got, err := template.Sprint(input)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if got != want {
+					t.Errorf("want '%s', got '%s'", want, got)
+					return
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+}
+
+func TestFuncDoesNotMutate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		operand any
+		options map[string]any
+	}{
+		"currency": {operand: 3, options: map[string]any{"currency": "EUR"}},
+		"date":     {operand: time.Unix(0, 0), options: map[string]any{"style": "long", "timeZone": "Europe/Riga"}},
+		"datetime": {operand: time.Unix(0, 0), options: map[string]any{"dateStyle": "long", "timeZone": "Europe/Riga"}},
+		"integer":  {operand: 3.5, options: map[string]any{"minimumIntegerDigits": 2}},
+		"number":   {operand: 3.5, options: map[string]any{"minimumFractionDigits": 2}},
+		"offset":   {operand: 3, options: map[string]any{"add": 1}},
+		"percent":  {operand: 0.5, options: map[string]any{"signDisplay": "always"}},
+		"string":   {operand: "hello"},
+		"time":     {operand: time.Unix(0, 0), options: map[string]any{"style": "short", "timeZone": "Europe/Riga"}},
+	}
+
+	for name, f := range NewRegistry() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			test, ok := tests[name]
+			if !ok {
+				t.Fatalf(`want test of the function "%s"`, name)
+			}
+
+			operand := NewResolvedValue(test.operand)
+			options := make(Options, len(test.options))
+
+			for k, v := range test.options {
+				options[k] = NewResolvedValue(v)
+			}
+
+			want := maps.Clone(options)
+
+			result, err := f(operand, options, language.English)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result == operand {
+				t.Error("want new result, got operand")
+			}
+
+			if operand.format != nil || operand.selectKey != nil || operand.function != "" || operand.options != nil {
+				t.Errorf("want unmodified operand, got %+v", operand)
+			}
+
+			if !maps.Equal(want, options) {
+				t.Errorf("want options %v, got %v", slices.Sorted(maps.Keys(want)), slices.Sorted(maps.Keys(options)))
+			}
+
+			for k, v := range options {
+				if v.format != nil || v.selectKey != nil || v.function != "" {
+					t.Errorf(`want unmodified option "%s", got %+v`, k, v)
+				}
+			}
+
+			// the format function does not modify the captured state
+			var wg sync.WaitGroup
+
+			for range 4 {
+				wg.Go(func() { _ = result.String() })
+			}
+
+			wg.Wait()
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package template_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -110,5 +112,67 @@ func ExampleTemplate_complexMessage() {
 	// Output: John is 42 years old and his favorite color is 255,0,0.
 }
 
+func ExampleTemplate_FormatToParts() {
+	// Define a MF2 string.
+	const input = "{#b}{ $count :number }{/b} new messages"
+
+	// Parse template.
+	t, err := template.New().Parse(input)
+	if err != nil {
+		panic(err)
+	}
+
+	// Format the template to parts.
+	parts, err := t.FormatToParts(map[string]any{"count": 1200})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, part := range parts {
+		fmt.Printf("%s %s %q\n", part.Type, part.Kind, part.Value)
+	}
+
+	// Output:
+	// markup open ""
+	// number  "1,200"
+	// markup close ""
+	// text  " new messages"
+}
+
+func ExampleTemplate_ExecuteContext() {
+	type userKey struct{}
+
+	// Define a MF2 string.
+	const input = "Welcome back, { :user:name }!"
+
+	// The function looks up the request-scoped user.
+	userName := func(
+		ctx context.Context, _ template.FuncContext, _ *template.ResolvedValue, _ template.Options,
+	) (*template.ResolvedValue, error) {
+		name, ok := ctx.Value(userKey{}).(string)
+		if !ok {
+			return nil, errors.New("exec user:name function: no user")
+		}
+
+		return template.NewResolvedValue(name), nil
+	}
+
+	// Parse template.
+	t, err := template.New(template.WithContextFunc("user:name", userName)).Parse(input)
+	if err != nil {
+		panic(err)
+	}
+
+	// Execute the template with the request context.
+	ctx := context.WithValue(context.Background(), userKey{}, "Jane")
+
+	err = t.ExecuteContext(ctx, os.Stdout, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Output: Welcome back, Jane!
+}
+
 // TODO(mvilks): come up with a good example of the ResolvedValue usage that requires access to the raw value.
 // E.g. function ":parity" that returns a localized name for "odd"/"even".
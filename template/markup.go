@@ -0,0 +1,289 @@
+package template
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	ast "go.expect.digital/mf2/parse"
+)
+
+// MarkupKind is the kind of the markup placeholder.
+type MarkupKind string
+
+// Kinds of the markup placeholders.
+const (
+	MarkupOpen       MarkupKind = "open"       // {#b}
+	MarkupClose      MarkupKind = "close"      // {/b}
+	MarkupStandalone MarkupKind = "standalone" // {#br/}
+)
+
+// Markup is the resolved markup placeholder.
+type Markup struct {
+	// The options of the markup, except the options in the "u" namespace.
+	Options Options
+	// The attributes of the markup. The attribute without a value is true.
+	Attributes Options
+	// The identifier of the markup, e.g. "b" or "html:b".
+	Name string
+	// The value of the u:id option.
+	ID   string
+	Kind MarkupKind
+}
+
+// MarkupHandler renders the markup placeholders. By default, markup placeholders
+// format to an empty string.
+type MarkupHandler interface {
+	// Markup writes the rendered markup to w.
+	Markup(w io.Writer, markup Markup) error
+}
+
+// MarkupHandlerFunc is an adapter to use the function as [MarkupHandler].
+type MarkupHandlerFunc func(w io.Writer, markup Markup) error
+
+// Markup calls f(w, markup).
+func (f MarkupHandlerFunc) Markup(w io.Writer, markup Markup) error {
+	return f(w, markup)
+}
+
+// WithMarkupHandler sets the handler to render the markup placeholders.
+func WithMarkupHandler(h MarkupHandler) Option {
+	return func(t *Template) {
+		t.markupHandler = h
+	}
+}
+
+// HTMLMarkupHandler renders the markup as HTML elements. Only the markup with the allowed tag names
+// is rendered, the other markup formats to an empty string. The allowed options are rendered as escaped
+// attributes of the element:
+//
+//   - the event handler attributes (on*) and the attributes not in the allowlist, e.g. style, are skipped;
+//   - the URL of href and src is skipped unless it is relative or has the http, https or mailto scheme.
+//
+// The text and the placeholders of the message are HTML-escaped.
+//
+// Example:
+//
+//	{#a href=|https://example.com|}link{/a}{#br/} -> <a href="https://example.com">link</a><br/>
+//	{#a href=|javascript:alert(1)| onclick=|alert(1)|}<link>{/a} -> <a>&lt;link&gt;</a>
+func HTMLMarkupHandler(allowedTags ...string) MarkupHandler {
+	return htmlMarkupHandler{allowedTags: allowedTags}
+}
+
+// htmlMarkupHandler is the markup handler returned by [HTMLMarkupHandler].
+type htmlMarkupHandler struct {
+	allowedTags []string
+}
+
+// Markup writes the markup as HTML element.
+func (h htmlMarkupHandler) Markup(w io.Writer, markup Markup) error {
+	if !slices.Contains(h.allowedTags, markup.Name) {
+		return nil
+	}
+
+	var sb strings.Builder
+
+	switch markup.Kind {
+	case MarkupClose:
+		sb.WriteString("</" + markup.Name + ">")
+	case MarkupOpen, MarkupStandalone:
+		sb.WriteString("<" + markup.Name)
+
+		for _, name := range slices.Sorted(maps.Keys(markup.Options)) {
+			value := markup.Options[name].String()
+
+			if !isHTMLAttributeName(name) || !isHTMLAttributeValue(name, value) {
+				continue
+			}
+
+			sb.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+		}
+
+		if markup.Kind == MarkupStandalone {
+			sb.WriteString("/")
+		}
+
+		sb.WriteString(">")
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("write html markup: %w", err)
+	}
+
+	return nil
+}
+
+// escapeText escapes the text and the placeholders of the message.
+func (h htmlMarkupHandler) escapeText(s string) string {
+	return html.EscapeString(s)
+}
+
+// textEscaper is implemented by the markup handler escaping the text and the placeholders
+// written between the markup, e.g. [HTMLMarkupHandler].
+type textEscaper interface {
+	escapeText(s string) string
+}
+
+// htmlAttributes are the attributes rendered by [HTMLMarkupHandler], except the "aria-*" and "data-*" attributes.
+var htmlAttributes = []string{
+	"alt", "class", "dir", "height", "href", "hreflang", "id", "lang", "rel", "src", "target", "title", "width",
+}
+
+// isHTMLAttributeName returns true if the option name is safe to render as HTML attribute name.
+func isHTMLAttributeName(name string) bool {
+	for i, r := range name {
+		switch {
+		case isAlpha(r):
+		case i > 0 && ('0' <= r && r <= '9' || r == '-' || r == '_'):
+		default:
+			return false
+		}
+	}
+
+	name = strings.ToLower(name)
+
+	switch {
+	case name == "", strings.HasPrefix(name, "on"):
+		return false
+	case strings.HasPrefix(name, "aria-"), strings.HasPrefix(name, "data-"):
+		return true
+	default:
+		return slices.Contains(htmlAttributes, name)
+	}
+}
+
+// isHTMLAttributeValue returns true if the value is safe to render as the value of the attribute.
+// The URL must be relative or have the http, https or mailto scheme.
+func isHTMLAttributeValue(name, value string) bool {
+	switch strings.ToLower(name) {
+	default:
+		return true
+	case "href", "src":
+		u, err := url.Parse(value)
+		if err != nil {
+			return false
+		}
+
+		return slices.Contains([]string{"", "http", "https", "mailto"}, u.Scheme)
+	}
+}
+
+// isAlpha returns true if r is ASCII letter.
+func isAlpha(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+// ANSIMarkupHandler renders the markup as ANSI terminal styles. The other markup formats to an empty string.
+//
+// Supported markup:
+//
+//	{#b} or {#bold}       - bold
+//	{#dim}                - dim
+//	{#i}, {#em} or {#italic} - italic
+//	{#u} or {#underline}  - underline
+//	{#s} or {#strike}     - strikethrough
+//	{#black}, {#red}, {#green}, {#yellow}, {#blue}, {#magenta}, {#cyan}, {#white} - foreground color
+func ANSIMarkupHandler() MarkupHandler {
+	return MarkupHandlerFunc(func(w io.Writer, markup Markup) error {
+		style, ok := ansiStyles[markup.Name]
+		if !ok {
+			return nil
+		}
+
+		var code string
+
+		switch markup.Kind {
+		case MarkupOpen:
+			code = style[0]
+		case MarkupClose:
+			code = style[1]
+		case MarkupStandalone:
+			return nil
+		}
+
+		if _, err := io.WriteString(w, "\x1b["+code+"m"); err != nil {
+			return fmt.Errorf("write ansi markup: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ansiStyles contains SGR parameters to set and reset the style.
+var ansiStyles = map[string][2]string{
+	"b":         {"1", "22"},
+	"bold":      {"1", "22"},
+	"dim":       {"2", "22"},
+	"i":         {"3", "23"},
+	"em":        {"3", "23"},
+	"italic":    {"3", "23"},
+	"u":         {"4", "24"},
+	"underline": {"4", "24"},
+	"s":         {"9", "29"},
+	"strike":    {"9", "29"},
+	"black":     {"30", "39"},
+	"red":       {"31", "39"},
+	"green":     {"32", "39"},
+	"yellow":    {"33", "39"},
+	"blue":      {"34", "39"},
+	"magenta":   {"35", "39"},
+	"cyan":      {"36", "39"},
+	"white":     {"37", "39"},
+}
+
+// resolveMarkupValue resolves the options and the attributes of the markup.
+func (e *executer) resolveMarkupValue(m ast.Markup) (Markup, error) {
+	options, err := e.resolveOptions(m.Options)
+	if err != nil {
+		return Markup{}, fmt.Errorf("markup: %w", err)
+	}
+
+	markup := Markup{Name: m.Identifier.String()}
+
+	if id, ok := options["u:id"]; ok {
+		markup.ID = id.String()
+	}
+
+	for name := range options {
+		if strings.HasPrefix(name, "u:") {
+			delete(options, name)
+		}
+	}
+
+	if len(options) > 0 {
+		markup.Options = options
+	}
+
+	for _, attr := range m.Attributes {
+		if markup.Attributes == nil {
+			markup.Attributes = make(Options, len(m.Attributes))
+		}
+
+		if attr.Value == nil {
+			markup.Attributes[attr.Identifier.String()] = NewResolvedValue(true)
+			continue
+		}
+
+		value, err := e.resolveValue(attr.Value)
+		if err != nil {
+			return Markup{}, fmt.Errorf("markup attribute: %w", err)
+		}
+
+		markup.Attributes[attr.Identifier.String()] = NewResolvedValue(value)
+	}
+
+	switch m.Typ {
+	case ast.Open:
+		markup.Kind = MarkupOpen
+	case ast.Close:
+		markup.Kind = MarkupClose
+	case ast.SelfClose, ast.Unspecified:
+		markup.Kind = MarkupStandalone
+	}
+
+	return markup, nil
+}
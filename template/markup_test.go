@@ -0,0 +1,210 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.expect.digital/mf2"
+)
+
+func Test_MarkupHandler(t *testing.T) {
+	t.Parallel()
+
+	var got []Markup
+
+	handler := MarkupHandlerFunc(func(w io.Writer, markup Markup) error {
+		got = append(got, markup)
+
+		if _, err := io.WriteString(w, "["+string(markup.Kind)+" "+markup.Name+"]"); err != nil {
+			return fmt.Errorf("write markup: %w", err)
+		}
+
+		return nil
+	})
+
+	template, err := New(WithMarkupHandler(handler)).Parse("{#b class=$c u:id=x @translate=no @track}bold{/b}{#br/}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := template.Sprint(map[string]any{"c": "big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[open b]bold[close b][standalone br]"; want != s {
+		t.Errorf("want '%s', got '%s'", want, s)
+	}
+
+	want := []Markup{
+		{
+			Name:    "b",
+			ID:      "x",
+			Kind:    MarkupOpen,
+			Options: Options{"class": NewResolvedValue("big")},
+			Attributes: Options{
+				"translate": NewResolvedValue("no"),
+				"track":     NewResolvedValue(true),
+			},
+		},
+		{Name: "b", Kind: MarkupClose},
+		{Name: "br", Kind: MarkupStandalone},
+	}
+
+	if len(want) != len(got) {
+		t.Fatalf("want %d markup, got %d", len(want), len(got))
+	}
+
+	for i := range want {
+		if want[i].Name != got[i].Name || want[i].ID != got[i].ID || want[i].Kind != got[i].Kind {
+			t.Errorf("want %+v, got %+v", want[i], got[i])
+		}
+
+		for _, opts := range [][2]Options{{want[i].Options, got[i].Options}, {want[i].Attributes, got[i].Attributes}} {
+			if len(opts[0]) != len(opts[1]) {
+				t.Errorf("want options %v, got %v", opts[0], opts[1])
+			}
+
+			for k, v := range opts[0] {
+				if !reflect.DeepEqual(v.value, opts[1][k].value) {
+					t.Errorf("want option %s '%v', got '%v'", k, v.value, opts[1][k].value)
+				}
+			}
+		}
+	}
+}
+
+func Test_MarkupHandlerErrors(t *testing.T) {
+	t.Parallel()
+
+	handlerErr := errors.New("handler error")
+	handler := MarkupHandlerFunc(func(io.Writer, Markup) error { return handlerErr })
+
+	template, err := New(WithMarkupHandler(handler)).Parse("{#b}bold{/b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := template.Sprint(nil); !errors.Is(err, handlerErr) {
+		t.Errorf("want error '%v', got '%v'", handlerErr, err)
+	}
+
+	// unresolved option skips the markup
+	template, err = New(WithMarkupHandler(HTMLMarkupHandler("b"))).Parse("{#b class=$c}bold{/b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := template.Sprint(nil)
+	if !errors.Is(err, mf2.ErrUnresolvedVariable) {
+		t.Errorf("want error '%v', got '%v'", mf2.ErrUnresolvedVariable, err)
+	}
+
+	if want := "bold</b>"; want != s {
+		t.Errorf("want '%s', got '%s'", want, s)
+	}
+}
+
+func Test_HTMLMarkupHandler(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		input map[string]any
+		name  string
+		text  string
+		want  string
+	}{
+		{
+			name: "allowed",
+			text: "{#b}bold{/b} {#i}italic{/i}",
+			want: "<b>bold</b> <i>italic</i>",
+		},
+		{
+			name: "not allowed",
+			text: "{#script}alert(1){/script}",
+			want: "alert(1)",
+		},
+		{
+			name:  "escaped attributes",
+			text:  "{#a href=$url title=|Say \"hi\"|}link{/a}",
+			input: map[string]any{"url": "https://example.com/?a=1&b=2"},
+			want:  `<a href="https://example.com/?a=1&amp;b=2" title="Say &#34;hi&#34;">link</a>`,
+		},
+		{
+			name: "standalone",
+			text: "line{#br/}",
+			want: "line<br/>",
+		},
+		{
+			name: "u options and namespaced options are not rendered",
+			text: "{#b u:id=x html:onclick=|alert(1)|}bold{/b}",
+			want: "<b>bold</b>",
+		},
+		{
+			name: "event handler attributes are not rendered",
+			text: "{#b onclick=|alert(1)| ONMOUSEOVER=|alert(1)| class=x}bold{/b}",
+			want: `<b class="x">bold</b>`,
+		},
+		{
+			name: "attributes not in allowlist are not rendered",
+			text: "{#b style=|background:url(x)| formaction=x data-id=1 aria-label=Bold}bold{/b}",
+			want: `<b aria-label="Bold" data-id="1">bold</b>`,
+		},
+		{
+			name:  "javascript url is not rendered",
+			text:  "{#a href=$url}link{/a} {#a href=|JavaScript:alert(1)|}link{/a} {#img src=|data:text/html,x|/}",
+			input: map[string]any{"url": " javascript:alert(1)"},
+			want:  "<a>link</a> <a>link</a> <img/>",
+		},
+		{
+			name: "allowed urls",
+			text: "{#a href=|mailto:a@example.com|}mail{/a} {#a href=|/path?a=1|}path{/a} {#img src=|http://example.com/a.png|/}",
+			want: `<a href="mailto:a@example.com">mail</a> <a href="/path?a=1">path</a> <img src="http://example.com/a.png"/>`,
+		},
+		{
+			name:  "escaped text and placeholders",
+			text:  "<script>alert(1)</script> {$name} {|<i>|}",
+			input: map[string]any{"name": "<img src=x onerror=alert(1)>"},
+			want:  "&lt;script&gt;alert(1)&lt;/script&gt; &lt;img src=x onerror=alert(1)&gt; &lt;i&gt;",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			template, err := New(WithMarkupHandler(HTMLMarkupHandler("a", "b", "i", "br", "img"))).Parse(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := template.Sprint(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != got {
+				t.Errorf("want '%s', got '%s'", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_ANSIMarkupHandler(t *testing.T) {
+	t.Parallel()
+
+	template, err := New(WithMarkupHandler(ANSIMarkupHandler())).Parse("{#b}bold{/b} {#red}red{/red} {#unknown}text{/unknown}{#b/}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := template.Sprint(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "\x1b[1mbold\x1b[22m \x1b[31mred\x1b[39m text"; want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
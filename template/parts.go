@@ -0,0 +1,285 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/number"
+
+	ast "go.expect.digital/mf2/parse"
+)
+
+// PartType is the type of the formatted part.
+type PartType string
+
+// Types of the formatted parts.
+//
+// See ".message-format-wg/spec/formatting.md#formatting-to-a-sequence-of-parts".
+const (
+	PartText          PartType = "text"
+	PartString        PartType = "string"
+	PartNumber        PartType = "number"
+	PartDatetime      PartType = "datetime"
+	PartMarkup        PartType = "markup"
+	PartFallback      PartType = "fallback"
+	PartBidiIsolation PartType = "bidiIsolation"
+
+	// Sub-parts of the formatted number.
+
+	PartInteger     PartType = "integer"
+	PartGroup       PartType = "group"
+	PartDecimal     PartType = "decimal"
+	PartFraction    PartType = "fraction"
+	PartMinusSign   PartType = "minusSign"
+	PartPlusSign    PartType = "plusSign"
+	PartPercentSign PartType = "percentSign"
+	PartCurrency    PartType = "currency"
+	PartLiteral     PartType = "literal"
+)
+
+// Part is a part of the formatted message.
+type Part struct {
+	// The locale of the formatted expression.
+	Locale language.Tag
+	// The options of the markup, except the options in the "u" namespace.
+	Options Options
+	// The type of the part.
+	Type PartType
+	// The formatted value of the expression, the text or the bidi isolation character.
+	// Empty for markup.
+	Value string
+	// The source of the expression, e.g. "$var", "|literal|" or ":function".
+	Source string
+	// The direction of the formatted expression (ltr, rtl, auto).
+	Dir string
+	// The value of the u:id option of the expression or markup.
+	ID string
+	// The name of the markup.
+	Name string
+	// The kind of the markup.
+	Kind MarkupKind
+	// The sub-parts of the formatted number, e.g. integer, group, decimal and fraction.
+	Parts []Part
+}
+
+// FormatToParts executes the template and returns the formatted parts. The parts are returned
+// together with the error if the message is formatted with fallback values.
+func (t *Template) FormatToParts(input map[string]any) ([]Part, error) {
+	return t.FormatToPartsContext(context.Background(), input)
+}
+
+// FormatToPartsContext executes the template like [Template.FormatToParts]. The context is passed to
+// the functions of [WithContextFunc]. The execution stops with the context error when ctx is done.
+func (t *Template) FormatToPartsContext(ctx context.Context, input map[string]any) ([]Part, error) {
+	if t.ast == nil {
+		return nil, errors.New("format to parts: AST is nil")
+	}
+
+	executer, err := t.newExecuter(ctx, nil, input)
+	if err != nil {
+		return nil, fmt.Errorf("format to parts: %w", err)
+	}
+
+	executer.toParts = true
+
+	err = executer.execute()
+	if err != nil {
+		return executer.parts, fmt.Errorf("format to parts: %w", err)
+	}
+
+	return executer.parts, nil
+}
+
+// appendExpressionParts appends the formatted expression surrounded by the optional bidi isolation characters.
+func (e *executer) appendExpressionParts(expr ast.Expression, resolved *ResolvedValue, start, end string) {
+	if start != "" {
+		e.parts = append(e.parts, Part{Type: PartBidiIsolation, Value: start})
+	}
+
+	value := resolved.String()
+
+	if resolved.fallback {
+		source := strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}")
+		e.parts = append(e.parts, Part{Type: PartFallback, Value: value, Source: source})
+	} else {
+		part := Part{
+			Type:   valuePartType(resolved.value),
+			Value:  value,
+			Source: exprSource(expr),
+			Locale: resolved.locale,
+			Dir:    resolved.dir,
+			ID:     resolved.id,
+		}
+
+		if part.Locale == language.Und {
+			part.Locale = e.template.locale
+		}
+
+		if part.Dir == "" {
+			part.Dir = "auto"
+		}
+
+		if part.Type == PartNumber {
+			part.Parts = numberParts(value, part.Locale, currencyText(resolved, part.Locale))
+		}
+
+		e.parts = append(e.parts, part)
+	}
+
+	if end != "" {
+		e.parts = append(e.parts, Part{Type: PartBidiIsolation, Value: end})
+	}
+}
+
+// appendMarkupPart appends the markup with the resolved options.
+func (e *executer) appendMarkupPart(m ast.Markup) error {
+	markup, err := e.resolveMarkupValue(m)
+	if err != nil {
+		return err
+	}
+
+	e.parts = append(e.parts, Part{
+		Type:    PartMarkup,
+		Name:    markup.Name,
+		ID:      markup.ID,
+		Kind:    markup.Kind,
+		Options: markup.Options,
+	})
+
+	return nil
+}
+
+// valuePartType returns the part type of the resolved value.
+func valuePartType(value any) PartType {
+	switch value.(type) {
+	default:
+		return PartString
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return PartNumber
+	case time.Time:
+		return PartDatetime
+	}
+}
+
+// numberParts splits the formatted number into the sub-parts. The symbols of the locale are taken from
+// the number formatter, curr is the currency as displayed by :currency.
+func numberParts(s string, locale language.Tag, curr string) []Part {
+	symbols := newNumberSymbols(locale)
+
+	var (
+		parts    []Part
+		fraction bool
+	)
+
+	add := func(typ PartType, value string) {
+		if n := len(parts); n > 0 && parts[n-1].Type == typ {
+			parts[n-1].Value += value
+			return
+		}
+
+		parts = append(parts, Part{Type: typ, Value: value})
+	}
+
+	// isSeparator returns true if the separator precedes a digit.
+	isSeparator := func(v, separator string) bool {
+		return separator != "" && strings.HasPrefix(v, separator) && symbols.digitLen(v[len(separator):]) > 0
+	}
+
+	for i := 0; i < len(s); {
+		v := s[i:]
+		typ, size := PartLiteral, 0
+
+		switch {
+		case curr != "" && strings.HasPrefix(v, curr):
+			typ, size = PartCurrency, len(curr)
+		case symbols.digitLen(v) > 0:
+			typ, size = PartInteger, symbols.digitLen(v)
+			if fraction {
+				typ = PartFraction
+			}
+		case !fraction && isSeparator(v, symbols.decimal):
+			typ, size, fraction = PartDecimal, len(symbols.decimal), true
+		case !fraction && len(parts) > 0 && parts[len(parts)-1].Type == PartInteger && isSeparator(v, symbols.group):
+			typ, size = PartGroup, len(symbols.group)
+		case symbols.minus != "" && strings.HasPrefix(v, symbols.minus):
+			typ, size = PartMinusSign, len(symbols.minus)
+		case symbols.percent != "" && strings.HasPrefix(v, symbols.percent):
+			typ, size = PartPercentSign, len(symbols.percent)
+		// the sign added by the signDisplay option
+		case v[0] == '-':
+			typ, size = PartMinusSign, 1
+		case v[0] == '+':
+			typ, size = PartPlusSign, 1
+		}
+
+		if size == 0 {
+			_, size = utf8.DecodeRuneInString(v)
+		}
+
+		add(typ, v[:size])
+
+		i += size
+	}
+
+	return parts
+}
+
+// numberSymbols are the symbols of the numbers formatted in the locale.
+type numberSymbols struct {
+	decimal string
+	group   string
+	minus   string
+	percent string
+	digits  [10]string // the digits of the numbering system
+}
+
+// newNumberSymbols returns the symbols of the locale as formatted by the number formatter.
+func newNumberSymbols(locale language.Tag) numberSymbols {
+	p := newPrinter(locale)
+
+	var symbols numberSymbols
+
+	for d := range symbols.digits {
+		symbols.digits[d] = p.Sprint(number.Decimal(d))
+	}
+
+	// symbol returns the formatted number without the digits and the surrounding spaces.
+	symbol := func(num number.Formatter) string {
+		s := p.Sprint(num)
+
+		for _, digit := range symbols.digits {
+			s = strings.ReplaceAll(s, digit, "")
+		}
+
+		return strings.TrimFunc(s, unicode.IsSpace)
+	}
+
+	symbols.decimal = symbol(number.Decimal(1.5))
+	symbols.group = p.Sprint(number.Decimal(12345))
+	symbols.minus = symbol(number.Decimal(-1))
+	symbols.percent = symbol(number.Percent(0.01))
+
+	// the group separator can be a space, it is not trimmed
+	for _, digit := range symbols.digits {
+		symbols.group = strings.ReplaceAll(symbols.group, digit, "")
+	}
+
+	return symbols
+}
+
+// digitLen returns the length of the digit at the start of s, or zero.
+func (s numberSymbols) digitLen(v string) int {
+	for _, digit := range s.digits {
+		if digit != "" && strings.HasPrefix(v, digit) {
+			return len(digit)
+		}
+	}
+
+	return 0
+}
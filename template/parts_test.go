@@ -0,0 +1,240 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.expect.digital/mf2"
+	"golang.org/x/text/language"
+)
+
+func Test_FormatToParts(t *testing.T) {
+	t.Parallel()
+
+	en := language.AmericanEnglish
+
+	for _, test := range []struct {
+		input    map[string]any
+		name     string
+		text     string
+		want     []Part
+		wantErr  error
+		strategy BidiIsolation
+	}{
+		{
+			name: "text and string",
+			text: "Hello, {$name}!",
+			input: map[string]any{
+				"name": "World",
+			},
+			want: []Part{
+				{Type: PartText, Value: "Hello, "},
+				{Type: PartString, Value: "World", Source: "$name", Locale: en, Dir: "auto"},
+				{Type: PartText, Value: "!"},
+			},
+		},
+		{
+			name: "number",
+			text: "{-1234.5 :number signDisplay=auto u:id=n}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "-1,234.5", Source: "|-1234.5|", Locale: en, Dir: "ltr", ID: "n",
+					Parts: []Part{
+						{Type: PartMinusSign, Value: "-"},
+						{Type: PartInteger, Value: "1"},
+						{Type: PartGroup, Value: ","},
+						{Type: PartInteger, Value: "234"},
+						{Type: PartDecimal, Value: "."},
+						{Type: PartFraction, Value: "5"},
+					},
+				},
+			},
+		},
+		{
+			name: "number in locale",
+			text: "{1234.5 :number u:locale=lv}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "1\u00a0234,5", Source: "|1234.5|", Locale: language.Latvian, Dir: "ltr",
+					Parts: []Part{
+						{Type: PartInteger, Value: "1"},
+						{Type: PartGroup, Value: "\u00a0"},
+						{Type: PartInteger, Value: "234"},
+						{Type: PartDecimal, Value: ","},
+						{Type: PartFraction, Value: "5"},
+					},
+				},
+			},
+		},
+		{
+			name: "currency",
+			text: "{42 :currency currency=EUR}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "€42.00", Source: "|42|", Locale: en, Dir: "ltr",
+					Parts: []Part{
+						{Type: PartCurrency, Value: "€"},
+						{Type: PartInteger, Value: "42"},
+						{Type: PartDecimal, Value: "."},
+						{Type: PartFraction, Value: "00"},
+					},
+				},
+			},
+		},
+		{
+			name: "currency with sign in locale",
+			text: "{1234.5 :currency currency=EUR currencyDisplay=code signDisplay=always u:locale=lv}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "+1\u00a0234,50\u00a0EUR", Source: "|1234.5|", Locale: language.Latvian, Dir: "ltr",
+					Parts: []Part{
+						{Type: PartPlusSign, Value: "+"},
+						{Type: PartInteger, Value: "1"},
+						{Type: PartGroup, Value: "\u00a0"},
+						{Type: PartInteger, Value: "234"},
+						{Type: PartDecimal, Value: ","},
+						{Type: PartFraction, Value: "50"},
+						{Type: PartLiteral, Value: "\u00a0"},
+						{Type: PartCurrency, Value: "EUR"},
+					},
+				},
+			},
+		},
+		{
+			name: "percent in locale",
+			text: "{0.5 :percent u:locale=de}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "50\u00a0%", Source: "|0.5|", Locale: language.German, Dir: "ltr",
+					Parts: []Part{
+						{Type: PartInteger, Value: "50"},
+						{Type: PartLiteral, Value: "\u00a0"},
+						{Type: PartPercentSign, Value: "%"},
+					},
+				},
+			},
+		},
+		{
+			name: "native digits",
+			text: "{-1234 :number u:locale=ar}",
+			want: []Part{
+				{
+					Type: PartNumber, Value: "\u061c-١٬٢٣٤", Source: "|-1234|", Locale: language.Arabic, Dir: "rtl",
+					Parts: []Part{
+						{Type: PartMinusSign, Value: "\u061c-"},
+						{Type: PartInteger, Value: "١"},
+						{Type: PartGroup, Value: "٬"},
+						{Type: PartInteger, Value: "٢٣٤"},
+					},
+				},
+			},
+		},
+		{
+			name: "markup",
+			text: "{#link u:id=x}text{/link}{#br/}",
+			want: []Part{
+				{Type: PartMarkup, Name: "link", Kind: "open", ID: "x"},
+				{Type: PartText, Value: "text"},
+				{Type: PartMarkup, Name: "link", Kind: "close"},
+				{Type: PartMarkup, Name: "br", Kind: "standalone"},
+			},
+		},
+		{
+			name: "fallback",
+			text: "Hello, {$name}!",
+			want: []Part{
+				{Type: PartText, Value: "Hello, "},
+				{Type: PartFallback, Value: "{$name}", Source: "$name"},
+				{Type: PartText, Value: "!"},
+			},
+			wantErr: mf2.ErrUnresolvedVariable,
+		},
+		{
+			name:     "bidi isolation",
+			text:     "{world :string u:dir=rtl}",
+			strategy: BidiIsolationDefault,
+			want: []Part{
+				{Type: PartBidiIsolation, Value: "⁧"},
+				{Type: PartString, Value: "world", Source: "|world|", Locale: en, Dir: "rtl"},
+				{Type: PartBidiIsolation, Value: "⁩"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			template, err := New(WithBidiIsolation(test.strategy)).Parse(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := template.FormatToParts(test.input)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("want error '%v', got '%v'", test.wantErr, err)
+			}
+
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("want %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_FormatToPartsMarkupOptions(t *testing.T) {
+	t.Parallel()
+
+	template, err := New().Parse("{#link href=$url u:id=x}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := template.FormatToParts(map[string]any{"url": "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 1 {
+		t.Fatalf("want 1 part, got %d", len(parts))
+	}
+
+	if got := parts[0].Options["href"].String(); got != "https://example.com" {
+		t.Errorf("want href option 'https://example.com', got '%s'", got)
+	}
+
+	if _, ok := parts[0].Options["u:id"]; ok {
+		t.Error("want no u:id option")
+	}
+}
+
+func Test_FormatToPartsContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+
+	value := func(ctx context.Context, _ FuncContext, _ *ResolvedValue, _ Options) (*ResolvedValue, error) {
+		return NewResolvedValue(ctx.Value(key{})), nil
+	}
+
+	template, err := New(WithContextFunc("value", value)).Parse("{:value}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := template.FormatToPartsContext(context.WithValue(t.Context(), key{}, "value"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 1 || parts[0].Value != "value" {
+		t.Errorf("want 'value', got %+v", parts)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err = template.FormatToPartsContext(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("want '%s', got '%s'", context.Canceled, err)
+	}
+}
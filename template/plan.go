@@ -0,0 +1,266 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"go.expect.digital/mf2"
+	ast "go.expect.digital/mf2/parse"
+)
+
+// plan is the executable form of the message compiled by [Template.Parse]. The functions are looked up
+// in the registry, the literal options are resolved and the variant keys are collected once.
+// The plan is read-only and shared by the concurrent executions.
+type plan struct {
+	// matcher is the matcher of the complex message, nil otherwise.
+	matcher      *matcherPlan
+	declarations []declarationPlan
+	// pattern is the pattern of the simple message or the quoted pattern of the complex message.
+	pattern []partPlan
+	complex bool
+}
+
+type declarationPlan struct {
+	expr     *expressionPlan
+	variable string
+	input    bool
+}
+
+// partPlan is the text, the markup or the expression of the pattern.
+type partPlan struct {
+	part ast.PatternPart
+	expr *expressionPlan // nil for the text and the markup
+}
+
+type expressionPlan struct {
+	// err is the error of the unsupported annotation.
+	err error
+	// uErr is the error of the bad literal option in the "u" namespace.
+	uErr error
+	f    Func // nil if the function is unknown or the context function
+	// contextFunc is the function of [WithContextFunc], nil otherwise.
+	contextFunc ContextFunc
+	// options are the literal options without the options in the "u" namespace.
+	options Options
+	// resolved is the result of the expression without variables, resolved once by the builtin function
+	// for the locale of the template, see [Template.Bind].
+	resolved *ResolvedValue
+	// u are the literal options in the "u" namespace, the locale is [language.Und] without u:locale.
+	u      uOptions
+	locale language.Tag // the locale of the resolved expression
+	// funcName is the function of the annotation, or "string" for the literal without annotation.
+	// Empty for the variable without annotation.
+	funcName string
+	expr     ast.Expression
+	// dynamic is true if an option is set by a variable, the options are resolved on each execution.
+	dynamic bool
+	// builtin is true if f is the function of the default registry, see [Template.isBuiltin].
+	builtin bool
+}
+
+type matcherPlan struct {
+	selectors []string
+	// keys are the unique keys of each selector without the catch-all key, see [WithSelectKey].
+	keys     [][]string
+	variants []variantPlan
+}
+
+type variantPlan struct {
+	keys    []variantKey
+	pattern []partPlan
+}
+
+type variantKey struct {
+	// value is the raw string value of the key, e.g. "1" for both 1 and |1|.
+	value    string
+	catchAll bool
+}
+
+// compile compiles the message to the executable plan.
+func (t *Template) compile(message ast.Message) *plan {
+	p := new(plan)
+
+	switch m := message.(type) {
+	case ast.SimpleMessage:
+		p.pattern = t.compilePattern(m)
+	case ast.ComplexMessage:
+		p.complex = true
+
+		for _, decl := range m.Declarations {
+			switch d := decl.(type) {
+			case ast.LocalDeclaration:
+				p.declarations = append(p.declarations,
+					declarationPlan{variable: string(d.Variable), expr: t.compileExpression(d.Expression)})
+			case ast.InputDeclaration:
+				p.declarations = append(p.declarations, declarationPlan{
+					variable: string(d.Operand.(ast.Variable)), //nolint: forcetypeassert // always ast.Variable
+					expr:     t.compileExpression(ast.Expression(d)),
+					input:    true,
+				})
+			}
+		}
+
+		switch b := m.ComplexBody.(type) {
+		case ast.Matcher:
+			p.matcher = t.compileMatcher(b)
+		case ast.QuotedPattern:
+			p.pattern = t.compilePattern(b)
+		}
+	}
+
+	return p
+}
+
+func (t *Template) compilePattern(pattern []ast.PatternPart) []partPlan {
+	parts := make([]partPlan, 0, len(pattern))
+
+	for _, part := range pattern {
+		p := partPlan{part: part}
+
+		if expr, ok := part.(ast.Expression); ok {
+			p.expr = t.compileExpression(expr)
+		}
+
+		parts = append(parts, p)
+	}
+
+	return parts
+}
+
+func (t *Template) compileExpression(expr ast.Expression) *expressionPlan {
+	p := &expressionPlan{expr: expr}
+
+	switch v := expr.Annotation.(type) {
+	default:
+		p.err = fmt.Errorf(`expression: %T annotation "%s": %w`, v, v, mf2.ErrUnsupportedExpression)
+		return p
+	case ast.Function:
+		p.funcName = v.Identifier.String()
+		p.options = make(Options, len(v.Options))
+
+		for _, opt := range v.Options {
+			var value string
+
+			switch l := opt.Value.(type) {
+			case ast.QuotedLiteral:
+				value = string(l)
+			case ast.NameLiteral:
+				value = string(l)
+			default:
+				p.dynamic = true
+			}
+
+			p.options[opt.Identifier.String()] = NewResolvedValue(value)
+		}
+
+		if p.dynamic {
+			p.options = nil
+		} else {
+			p.u, p.uErr = resolveUOptions(p.options, language.Und)
+		}
+	case nil:
+		if _, ok := expr.Operand.(ast.Variable); !ok {
+			p.funcName = "string"
+		}
+	}
+
+	if p.funcName != "" {
+		p.f, p.contextFunc = t.registry[p.funcName], t.contextFuncs[p.funcName]
+		p.builtin = p.f != nil && t.isBuiltin(p.funcName)
+	}
+
+	// The builtin functions are deterministic, the result of the expression without variables is reused.
+	if _, ok := expr.Operand.(ast.Variable); !ok && !p.dynamic && p.builtin {
+		e := &executer{ctx: context.Background(), template: t}
+
+		resolved, err := e.resolveExpression(p)
+		if err == nil && resolved.err == nil {
+			p.resolved, p.locale = resolved, t.locale
+		}
+	}
+
+	return p
+}
+
+func (t *Template) compileMatcher(matcher ast.Matcher) *matcherPlan {
+	m := &matcherPlan{
+		selectors: make([]string, 0, len(matcher.Selectors)),
+		keys:      make([][]string, len(matcher.Selectors)),
+		variants:  make([]variantPlan, 0, len(matcher.Variants)),
+	}
+
+	for _, selector := range matcher.Selectors {
+		m.selectors = append(m.selectors, string(selector))
+	}
+
+	for _, variant := range matcher.Variants {
+		v := variantPlan{keys: make([]variantKey, 0, len(variant.Keys)), pattern: t.compilePattern(variant.QuotedPattern)}
+
+		for i, key := range variant.Keys {
+			// NOTE(mvilks): since collected keys will be compared to the selector,
+			// we need the keys's raw string value, not the representation of it
+			// e.g. the `1` should be equal to `|1|`
+			_, catchAll := key.(ast.CatchAllKey)
+			k := variantKey{value: keyString(key), catchAll: catchAll}
+
+			// add only unique keys
+			if !catchAll && i < len(m.keys) && !slices.Contains(m.keys[i], k.value) {
+				m.keys[i] = append(m.keys[i], k.value)
+			}
+
+			v.keys = append(v.keys, k)
+		}
+
+		m.variants = append(m.variants, v)
+	}
+
+	return m
+}
+
+// builtinRegistry is the default registry, see [NewRegistry]. It is read-only.
+var builtinRegistry = NewRegistry()
+
+// isBuiltin returns true if the function of the registry is the function of the default registry,
+// i.e. it is not replaced by [WithFunc] or [WithFuncs].
+func (t *Template) isBuiltin(name string) bool {
+	_, ok := builtinRegistry[name]
+
+	return ok && !t.customFuncs[name]
+}
+
+// maxPrinters is the maximum number of the cached printers. The locales can be set at runtime
+// by u:locale option, the printers of the locales beyond the limit are not cached.
+const maxPrinters = 64
+
+var (
+	// printers are the printers of the locales. [message.Printer] is safe for concurrent use.
+	printers     sync.Map // language.Tag -> *message.Printer
+	printerCount atomic.Int32
+)
+
+// newPrinter returns the printer of the locale.
+func newPrinter(locale language.Tag) *message.Printer {
+	if p, ok := printers.Load(locale); ok {
+		return p.(*message.Printer) //nolint:forcetypeassert // always *message.Printer
+	}
+
+	p := message.NewPrinter(locale)
+
+	if printerCount.Load() >= maxPrinters {
+		return p
+	}
+
+	if actual, loaded := printers.LoadOrStore(locale, p); loaded {
+		return actual.(*message.Printer) //nolint:forcetypeassert // always *message.Printer
+	}
+
+	printerCount.Add(1)
+
+	return p
+}
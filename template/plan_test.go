@@ -0,0 +1,119 @@
+package template
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func Test_Compile(t *testing.T) {
+	t.Parallel()
+
+	custom := func(*ResolvedValue, Options, language.Tag) (*ResolvedValue, error) {
+		return NewResolvedValue("custom"), nil
+	}
+
+	tests := []struct {
+		name, text string
+		// the expressions of the pattern resolved by compile
+		resolved []bool
+	}{
+		{
+			name:     "literals",
+			text:     "{1 :number} {|a|} {a :string}",
+			resolved: []bool{true, false, true, false, true},
+		},
+		{
+			name:     "variables",
+			text:     "{$a :number} {$a} {1 :number minimumFractionDigits=$a}",
+			resolved: []bool{false, false, false, false, false},
+		},
+		{
+			name:     "custom function",
+			text:     "{:custom} {1 :custom}",
+			resolved: []bool{false, false, false},
+		},
+		{
+			name:     "error",
+			text:     "{:number} {1 :unknown} {a :number}",
+			resolved: []bool{false, false, false, false, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			template, err := New(WithFunc("custom", custom)).Parse(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resolved []bool
+
+			for _, part := range template.plan.pattern {
+				resolved = append(resolved, part.expr != nil && part.expr.resolved != nil)
+			}
+
+			if !slices.Equal(test.resolved, resolved) {
+				t.Errorf("want %v, got %v", test.resolved, resolved)
+			}
+		})
+	}
+}
+
+func Test_CompileMatcher(t *testing.T) {
+	t.Parallel()
+
+	template, err := New().Parse(".input {$a :string} .input {$b :string} .match $a $b 1 |1| {{}} |1| 2 {{}} * * {{}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matcher := template.plan.matcher
+	if matcher == nil {
+		t.Fatal("want matcher, got nil")
+	}
+
+	if want := []string{"a", "b"}; !slices.Equal(want, matcher.selectors) {
+		t.Errorf("want selectors %v, got %v", want, matcher.selectors)
+	}
+
+	for i, want := range [][]string{{"1"}, {"1", "2"}} {
+		if !slices.Equal(want, matcher.keys[i]) {
+			t.Errorf("want keys %v, got %v", want, matcher.keys[i])
+		}
+	}
+
+	if want := []variantKey{{value: "*", catchAll: true}, {value: "*", catchAll: true}}; !slices.Equal(want, matcher.variants[2].keys) {
+		t.Errorf("want %v, got %v", want, matcher.variants[2].keys)
+	}
+}
+
+func Test_ExecuteCustomFuncEachTime(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+
+	counter := func(*ResolvedValue, Options, language.Tag) (*ResolvedValue, error) {
+		calls++
+		return NewResolvedValue(calls), nil
+	}
+
+	template, err := New(WithFunc("counter", counter)).Parse("{:counter}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"1", "2"} {
+		got, err := template.Sprint(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want != got {
+			t.Errorf("want '%s', got '%s'", want, got)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+
+	ast "go.expect.digital/mf2/parse"
+)
+
+// PluralIssue is the issue with the plural keys of the variants, see [CheckPluralKeys].
+type PluralIssue struct {
+	// The locale of the plural rules.
+	Locale language.Tag
+	// The selector variable.
+	Selector ast.Variable
+	// The unreachable key or the missing plural category.
+	Key string
+	// The plural selection, "plural" for cardinal or "ordinal".
+	Select string
+	// Missing is true if the plural category has no variant, otherwise the key is unreachable.
+	Missing bool
+}
+
+// String returns the description of the issue.
+func (i PluralIssue) String() string {
+	problem := "unreachable"
+	if i.Missing {
+		problem = "missing"
+	}
+
+	return fmt.Sprintf(`selector %s: %s key "%s" in "%s" %s selection`, i.Selector, problem, i.Key, i.Locale, i.Select)
+}
+
+// pluralForms are the keys of the plural categories.
+var pluralForms = map[string]plural.Form{
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+	"other": plural.Other,
+}
+
+// pluralFuncs are the functions selecting by the plural category, see [numberFunc].
+var pluralFuncs = []string{"number", "integer", "offset", "percent", "currency"}
+
+/*
+CheckPluralKeys reports the variant keys of the selectors with the plural or ordinal selection that
+are not plural categories of the locale, e.g. "few" in English, and the plural categories of the locale
+without a variant, e.g. "few" and "many" in Polish. The numeric keys are exact matches and are not reported.
+
+The selection and the locale of the selector are taken from the "select" and "u:locale" options
+of the declarations, the locale argument is used by default. The categories of the fractional numbers,
+e.g. "many" in Czech, are not required for the :integer selector or the selector with maximumFractionDigits=0.
+
+Example:
+
+	tree, _ := parse.Parse(".input {$n :number} .match $n one {{one}} few {{few}} * {{other}}")
+
+	for _, issue := range template.CheckPluralKeys(tree, language.English) {
+		fmt.Println(issue) // selector $n: unreachable key "few" in "en" plural selection
+	}
+*/
+func CheckPluralKeys(tree ast.AST, locale language.Tag) []PluralIssue {
+	message, ok := tree.Message.(ast.ComplexMessage)
+	if !ok {
+		return nil
+	}
+
+	matcher, ok := message.ComplexBody.(ast.Matcher)
+	if !ok {
+		return nil
+	}
+
+	var issues []PluralIssue
+
+	for i, selector := range matcher.Selectors {
+		selection, selectorLocale, integer, ok := pluralSelection(message.Declarations, selector, locale)
+		if !ok {
+			continue
+		}
+
+		issue := PluralIssue{Locale: selectorLocale, Selector: selector, Select: selection}
+		categories := pluralCategories(selectorLocale, selection, integer)
+
+		var keys []string
+
+		for _, variant := range matcher.Variants {
+			if i >= len(variant.Keys) {
+				continue
+			}
+
+			key, ok := literalKey(variant.Keys[i])
+			if !ok || slices.Contains(keys, key) {
+				continue
+			}
+
+			keys = append(keys, key)
+
+			if form, ok := pluralForms[key]; ok && slices.Contains(categories, form) || isNumericKey(key) {
+				continue
+			}
+
+			issue.Key = key
+			issues = append(issues, issue)
+		}
+
+		for _, form := range categories {
+			if key := pluralFormString(form); form != plural.Other && !slices.Contains(keys, key) {
+				issue.Key, issue.Missing = key, true
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	return issues
+}
+
+// CheckPluralKeys reports the issues with the plural keys of the variants
+// for the locale of the template, see [CheckPluralKeys].
+func (t *Template) CheckPluralKeys() []PluralIssue {
+	if t.ast == nil {
+		return nil
+	}
+
+	return CheckPluralKeys(*t.ast, t.locale)
+}
+
+// pluralSelection follows the declarations of the selector and returns the plural selection,
+// the locale of the selector and true if the selector is an integer, i.e. the function is :integer
+// or has maximumFractionDigits=0. It returns false if the selector is not selected by the plural category
+// or the selection is not known statically, e.g. the select option is set by the variable.
+func pluralSelection(
+	declarations []ast.Declaration, selector ast.Variable, locale language.Tag,
+) (string, language.Tag, bool, bool) {
+	var (
+		selection    string
+		localeOption bool
+		found        bool
+		integer      bool
+		// integerKnown is true if the nearest function sets the number of fraction digits
+		integerKnown bool
+	)
+
+	variable := selector
+
+	// the declarations cannot refer to each other in a cycle, the limit is a sanity check
+	for range len(declarations) {
+		expr, input, ok := declaredExpression(declarations, variable)
+		if !ok {
+			break
+		}
+
+		if function, ok := expr.Annotation.(ast.Function); ok {
+			// the options are not inherited from other functions
+			if !slices.Contains(pluralFuncs, function.Identifier.String()) {
+				break
+			}
+
+			found = true
+
+			if function.Identifier.String() == "integer" && !integerKnown {
+				integer, integerKnown = true, true
+			}
+
+			for _, option := range function.Options {
+				switch option.Identifier.String() {
+				case "select":
+					literal, ok := literalValue(option.Value)
+					if !ok && selection == "" {
+						return "", locale, false, false
+					}
+
+					if selection == "" {
+						selection = literal
+					}
+				case "maximumFractionDigits":
+					if literal, ok := literalValue(option.Value); ok && !integerKnown {
+						integer, integerKnown = literal == "0", true
+					}
+				case "u:locale":
+					if literal, ok := literalValue(option.Value); ok && !localeOption {
+						if tag, err := language.Parse(literal); err == nil {
+							locale, localeOption = tag, true
+						}
+					}
+				}
+			}
+		}
+
+		operand, ok := expr.Operand.(ast.Variable)
+		if !ok || input {
+			break
+		}
+
+		// the alias or the inherited options, e.g. .local $x = {$n :integer}
+		variable = operand
+	}
+
+	if !found {
+		return "", locale, false, false
+	}
+
+	switch selection {
+	default:
+		return "", locale, false, false
+	case "":
+		return "plural", locale, integer, true
+	case "plural", "ordinal":
+		return selection, locale, integer, true
+	}
+}
+
+// declaredExpression returns the expression of the variable declaration and true if it is an input declaration.
+func declaredExpression(declarations []ast.Declaration, variable ast.Variable) (ast.Expression, bool, bool) {
+	for _, declaration := range declarations {
+		switch d := declaration.(type) {
+		case ast.InputDeclaration:
+			if d.Operand == variable {
+				return ast.Expression(d), true, true
+			}
+		case ast.LocalDeclaration:
+			if d.Variable == variable {
+				return d.Expression, false, true
+			}
+		}
+	}
+
+	return ast.Expression{}, false, false
+}
+
+func literalValue(value ast.Value) (string, bool) {
+	switch v := value.(type) {
+	default:
+		return "", false
+	case ast.QuotedLiteral:
+		return string(v), true
+	case ast.NameLiteral:
+		return string(v), true
+	}
+}
+
+func literalKey(key ast.VariantKey) (string, bool) {
+	if literal, ok := key.(ast.Literal); ok {
+		return literalValue(literal)
+	}
+
+	return "", false
+}
+
+// isNumericKey returns true if the key is matched exactly by the numeric value, see [exactKey].
+func isNumericKey(key string) bool {
+	var n float64
+
+	return json.Unmarshal([]byte(key), &n) == nil
+}
+
+// pluralCategories returns the plural categories of the locale. The plural package does not
+// expose the categories, they are collected from the samples of the plural operands.
+// Only the integer operands are sampled for the integer selector.
+func pluralCategories(locale language.Tag, selection string, integer bool) []plural.Form {
+	rules := plural.Cardinal
+	if selection == "ordinal" {
+		rules = plural.Ordinal
+	}
+
+	var forms []plural.Form
+
+	add := func(i, v, f int) {
+		// w and t are v and f without the trailing zeros
+		w, t := v, f
+		for w > 0 && t%10 == 0 {
+			w, t = w-1, t/10
+		}
+
+		if form := rules.MatchPlural(locale, i, v, w, f, t); !slices.Contains(forms, form) {
+			forms = append(forms, form)
+		}
+	}
+
+	for i := range 1001 {
+		add(i, 0, 0)
+	}
+
+	for exp := 4; exp <= 9; exp++ {
+		add(int(math.Pow10(exp)), 0, 0)
+	}
+
+	if selection != "ordinal" && !integer {
+		for i := range 11 {
+			for v := 1; v <= 2; v++ {
+				for f := range int(math.Pow10(v)) {
+					add(i, v, f)
+				}
+			}
+		}
+	}
+
+	// in the CLDR order
+	order := []plural.Form{plural.Zero, plural.One, plural.Two, plural.Few, plural.Many, plural.Other}
+
+	return slices.DeleteFunc(order, func(form plural.Form) bool { return !slices.Contains(forms, form) })
+}
@@ -0,0 +1,162 @@
+package template
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	ast "go.expect.digital/mf2/parse"
+)
+
+func Test_pluralCategories(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		locale    language.Tag
+		selection string
+		want      []string
+		integer   bool
+	}{
+		{language.English, "plural", []string{"one", "other"}, false},
+		{language.English, "ordinal", []string{"one", "two", "few", "other"}, false},
+		{language.Polish, "plural", []string{"one", "few", "many", "other"}, false},
+		{language.Arabic, "plural", []string{"zero", "one", "two", "few", "many", "other"}, false},
+		{language.Japanese, "plural", []string{"other"}, false},
+		{language.Czech, "plural", []string{"one", "few", "many", "other"}, false},
+		{language.Czech, "plural", []string{"one", "few", "other"}, true},
+	} {
+		var got []string
+
+		for _, form := range pluralCategories(test.locale, test.selection, test.integer) {
+			got = append(got, pluralFormString(form))
+		}
+
+		if !slices.Equal(test.want, got) {
+			t.Errorf("%s %s: want %v, got %v", test.locale, test.selection, test.want, got)
+		}
+	}
+}
+
+func Test_CheckPluralKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		locale language.Tag
+		name   string
+		input  string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			locale: language.English,
+			input:  ".input {$n :number} .match $n 0 {{zero}} one {{one}} * {{other}}",
+		},
+		{
+			name:   "unreachable key",
+			locale: language.English,
+			input:  ".input {$n :number} .match $n one {{one}} few {{few}} foo {{foo}} * {{other}}",
+			want: []string{
+				`selector $n: unreachable key "few" in "en" plural selection`,
+				`selector $n: unreachable key "foo" in "en" plural selection`,
+			},
+		},
+		{
+			name:   "missing categories",
+			locale: language.Polish,
+			input:  ".input {$n :integer} .match $n one {{one}} * {{other}}",
+			want: []string{
+				`selector $n: missing key "few" in "pl" plural selection`,
+				`selector $n: missing key "many" in "pl" plural selection`,
+			},
+		},
+		{
+			name:   "ordinal",
+			locale: language.English,
+			input:  ".input {$n :number select=ordinal} .match $n one {{st}} two {{nd}} * {{th}}",
+			want: []string{
+				`selector $n: missing key "few" in "en" ordinal selection`,
+			},
+		},
+		{
+			name:   "inherited selection and locale",
+			locale: language.English,
+			input: ".input {$n :number select=ordinal u:locale=pl} .local $m = {$n :integer} .local $x = {$m} " +
+				".match $x one {{one}} * {{other}}",
+			want: []string{
+				`selector $x: unreachable key "one" in "pl" ordinal selection`,
+			},
+		},
+		{
+			name:   "integer",
+			locale: language.Czech,
+			input:  ".input {$n :integer} .match $n one {{}} few {{}} * {{}}",
+		},
+		{
+			name:   "number without fraction digits",
+			locale: language.Czech,
+			input: ".input {$n :number maximumFractionDigits=0} .local $x = {$n :number minimumIntegerDigits=2} " +
+				".match $x one {{}} few {{}} * {{}}",
+		},
+		{
+			name:   "number with fraction digits",
+			locale: language.Czech,
+			input: ".input {$n :integer} .local $x = {$n :number maximumFractionDigits=2} " +
+				".match $x one {{}} few {{}} * {{}}",
+			want: []string{
+				`selector $x: missing key "many" in "cs" plural selection`,
+			},
+		},
+		{
+			name:   "not plural",
+			locale: language.English,
+			input: ".input {$s :string} .input {$n :number select=exact} .input {$v :number select=$select} " +
+				".match $s $n $v few few few {{few}} * * * {{other}}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := ast.Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, issue := range CheckPluralKeys(tree, test.locale) {
+				got = append(got, issue.String())
+			}
+
+			if !slices.Equal(test.want, got) {
+				t.Errorf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_TemplateCheckPluralKeys(t *testing.T) {
+	t.Parallel()
+
+	template, err := New(WithLocale(language.Russian)).Parse(".input {$n :number} .match $n one {{one}} * {{other}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`selector $n: missing key "few" in "ru" plural selection`,
+		`selector $n: missing key "many" in "ru" plural selection`,
+	}
+
+	var got []string
+
+	for _, issue := range template.CheckPluralKeys() {
+		got = append(got, issue.String())
+	}
+
+	if !slices.Equal(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -2,7 +2,10 @@ package template
 
 import (
 	"cmp"
+	"context"
 	"fmt"
+	"log/slog"
+	"maps"
 	"reflect"
 	"slices"
 	"strconv"
@@ -14,10 +17,35 @@ import (
 
 // See ".message-format-wg/spec/registry.xml".
 
+// Func is a function of the registry. If the function returns the output together with the error,
+// the output is used and the error is reported, e.g. when a bad option is replaced with its default.
+//
+// The function is called concurrently by the concurrent executions of the template. It must not modify
+// the input, the options or any other shared state, use [NewResolvedValue] to create the output
+// from the input and the copy of the options to change them, e.g. with [maps.Clone].
 type Func func(input *ResolvedValue, options Options, locale language.Tag) (output *ResolvedValue, err error)
 
 type Registry map[string]Func
 
+// ContextFunc is a function that receives the context of [Template.ExecuteContext] and the [FuncContext]
+// of the call, e.g. to look up the request-scoped data or to respect the deadline. See [WithContextFunc].
+//
+// The same rules as for [Func] apply, the function must not modify the input, the options or any other shared state.
+type ContextFunc func(
+	ctx context.Context, fc FuncContext, input *ResolvedValue, options Options,
+) (output *ResolvedValue, err error)
+
+// FuncContext is the context of the function call.
+type FuncContext struct {
+	// Logger is the logger of the template, see [WithLogger].
+	Logger *slog.Logger
+	// Locale is the locale of the expression, set by the u:locale option or the locale of the template.
+	Locale language.Tag
+	// Dir is the direction set by the u:dir option (ltr, rtl, auto, inherit),
+	// or the direction of the locale (ltr, rtl).
+	Dir string
+}
+
 // Options are a possible options for the function.
 type Options map[string]*ResolvedValue
 
@@ -95,15 +123,39 @@ func (o Options) GetInt(name string, fallback int, validate ...Validate[int]) (i
 // NewRegistry returns a new registry with default functions.
 func NewRegistry() Registry {
 	return Registry{
+		"currency": currencyFunc,
 		"date":     dateFunc,
 		"datetime": datetimeFunc,
 		"integer":  integerFunc,
 		"number":   numberFunc,
+		"offset":   offsetFunc,
+		"percent":  percentFunc,
 		"string":   stringFunc,
 		"time":     timeFunc,
 	}
 }
 
+// mergeOptions returns a new [Options] with the inherited options
+// overridden by the options of the current annotation.
+func mergeOptions(inherited, options Options) Options {
+	merged := make(Options, len(inherited)+len(options))
+
+	maps.Copy(merged, inherited)
+	maps.Copy(merged, options)
+
+	return merged
+}
+
+// inheritOptions returns the options of the operand merged with the options of the current annotation
+// if the operand is resolved by one of the functions. Otherwise, the options are returned unchanged.
+func inheritOptions(operand *ResolvedValue, options Options, funcNames ...string) Options {
+	if !slices.Contains(funcNames, operand.function) {
+		return options
+	}
+
+	return mergeOptions(operand.options, options)
+}
+
 type Validate[T any] func(T) error
 
 func oneOf[T comparable](values ...T) func(T) error {
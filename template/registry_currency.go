@@ -0,0 +1,324 @@
+package template
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	"go.expect.digital/mf2"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+type currencyOptions struct {
+	// How to display the currency (code, symbol, narrowSymbol).
+	//
+	// NOTE: golang.org/x/text does not provide localized currency names,
+	// "name" is rejected with [mf2.ErrBadOption].
+	CurrencyDisplay string
+	// In many locales, accounting format means to wrap the number with parentheses
+	// instead of prepending a minus sign (standard, accounting).
+	CurrencySign string
+	// When to display the sign for the number (auto, always, exceptZero, negative, never).
+	SignDisplay string
+	// (auto, always, never, min2)
+	UseGrouping string
+	// The minimum number of integer digits to use.
+	MinimumIntegerDigits int
+	// The number of fraction digits to use. The default "auto" is the number of
+	// minor unit digits provided by the ISO 4217 currency code list.
+	FractionDigits int
+	// The currency to use in currency formatting.
+	// Possible values are the ISO 4217 currency codes, such as "USD" for the US dollar,
+	// "EUR" for the euro, or "CNY" for the Chinese RMB. The option is required.
+	Currency currency.Unit
+}
+
+// currencyOptionNames are the options of :currency.
+var currencyOptionNames = []string{
+	"currency", "currencyDisplay", "currencySign", "signDisplay", "useGrouping", "minimumIntegerDigits", "fractionDigits",
+}
+
+// parseCurrencyOptions parses :currency options.
+func parseCurrencyOptions(opts Options) (*currencyOptions, error) {
+	errorf := func(format string, args ...any) (*currencyOptions, error) {
+		return nil, fmt.Errorf("%w: "+format, append([]any{mf2.ErrBadOption}, args...)...)
+	}
+
+	for k := range opts {
+		if !slices.Contains(currencyOptionNames, k) {
+			return errorf("unsupported option: %s", k)
+		}
+	}
+
+	var (
+		err     error
+		options currencyOptions
+	)
+
+	curr, ok := opts["currency"]
+	if !ok {
+		return errorf("currency is required")
+	}
+
+	switch v := curr.value.(type) {
+	default:
+		return errorf("invalid currency type: %T", v)
+	case string:
+		options.Currency, err = currency.ParseISO(v)
+		if err != nil {
+			return errorf("invalid currency value: %s", v)
+		}
+
+		if options.Currency == currency.XXX {
+			return errorf("empty currency value")
+		}
+	case currency.Unit:
+		options.Currency = v
+	}
+
+	currencyDisplays := oneOf("code", "symbol", "narrowSymbol", "name")
+
+	options.CurrencyDisplay, err = opts.GetString("currencyDisplay", "symbol", currencyDisplays)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	if options.CurrencyDisplay == "name" {
+		return errorf(`currencyDisplay "name" is not supported, localized currency names are not available`)
+	}
+
+	currencySigns := oneOf("standard", "accounting")
+
+	options.CurrencySign, err = opts.GetString("currencySign", "standard", currencySigns)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	signDisplays := oneOf("auto", "always", "exceptZero", "negative", "never")
+
+	options.SignDisplay, err = opts.GetString("signDisplay", "auto", signDisplays)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	useGroupings := oneOf("auto", "always", "never", "min2")
+
+	options.UseGrouping, err = opts.GetString("useGrouping", "auto", useGroupings)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	options.MinimumIntegerDigits, err = opts.GetInt("minimumIntegerDigits", 1, eqOrGreaterThan(1))
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	scale, _ := currency.Standard.Rounding(options.Currency)
+
+	options.FractionDigits = scale
+
+	// "auto" or the number of fraction digits
+	if v, ok := opts["fractionDigits"]; ok && v.value != "auto" {
+		options.FractionDigits, err = opts.GetInt("fractionDigits", scale, eqOrGreaterThan(0))
+		if err != nil {
+			return errorf("%w", err)
+		}
+	}
+
+	return &options, nil
+}
+
+// currencyFunc is the implementation of the currency function. Locale-sensitive currency formatting.
+func currencyFunc(operand *ResolvedValue, options Options, locale language.Tag) (*ResolvedValue, error) {
+	errorf := func(format string, args ...any) (*ResolvedValue, error) {
+		return nil, fmt.Errorf("exec currency function: "+format, args...)
+	}
+
+	value, err := parseNumberOperand(operand)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	// The options of the previous numeric annotation are inherited,
+	// options of the current annotation take priority.
+	// The options not supported by :currency, e.g. minimumFractionDigits of :number, are not inherited.
+	if slices.Contains([]string{":number", ":integer", ":currency"}, operand.function) {
+		inherited := maps.Clone(operand.options)
+		maps.DeleteFunc(inherited, func(k string, _ *ResolvedValue) bool { return !slices.Contains(currencyOptionNames, k) })
+		options = mergeOptions(inherited, options)
+	}
+
+	opts, err := parseCurrencyOptions(options)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	p := newPrinter(locale)
+	numberOpts := []number.Option{
+		number.MinFractionDigits(opts.FractionDigits),
+		number.MaxFractionDigits(opts.FractionDigits),
+		number.MinIntegerDigits(opts.MinimumIntegerDigits),
+	}
+
+	if opts.UseGrouping == "never" {
+		numberOpts = append(numberOpts, number.NoSeparator())
+	}
+
+	abs := value
+	if abs < 0 {
+		abs = -abs
+	}
+
+	num := number.Decimal(abs, numberOpts...)
+
+	format := func() string {
+		amount := p.Sprint(num)
+		result := currencyPatternOf(locale).format(opts.display(p), amount)
+
+		if value < 0 && opts.CurrencySign == "accounting" && opts.SignDisplay != "never" {
+			return "(" + result + ")"
+		}
+
+		return withSign(result, value, opts.SignDisplay)
+	}
+
+	selectKey := func(keys []string) string {
+		if key, ok := exactKey(keys, round(value, opts.FractionDigits)); ok {
+			return key
+		}
+
+		digits := num.Digits(nil, locale, opts.FractionDigits)
+		form := plural.Cardinal.MatchDigits(locale, digits.Digits, int(digits.Exp), int(digits.End-digits.Exp))
+
+		return pluralFormString(form)
+	}
+
+	return NewResolvedValue(value,
+		WithFormat(format), WithSelectKey(selectKey), withFunction(":currency", options), withDir(locale)), nil
+}
+
+// display returns the currency as displayed by the currencyDisplay option.
+func (o *currencyOptions) display(p *message.Printer) string {
+	switch o.CurrencyDisplay {
+	default: // symbol
+		return p.Sprint(currency.Symbol(o.Currency))
+	case "code":
+		return o.Currency.String()
+	case "narrowSymbol":
+		return p.Sprint(currency.NarrowSymbol(o.Currency))
+	}
+}
+
+// currencyText returns the currency as displayed in the value formatted by :currency,
+// or the empty string if the value is not formatted by :currency.
+func currencyText(value *ResolvedValue, locale language.Tag) string {
+	if value.function != ":currency" {
+		return ""
+	}
+
+	opts, err := parseCurrencyOptions(value.options)
+	if err != nil {
+		return ""
+	}
+
+	return opts.display(newPrinter(locale))
+}
+
+// currencyPattern is the placement of the currency in the CLDR standard currency pattern of the locale,
+// e.g. "¤#,##0.00" in English and "#,##0.00 ¤" in Latvian. golang.org/x/text provides the currency symbols
+// but not the currency patterns.
+type currencyPattern struct {
+	suffix bool // the currency follows the amount
+	space  bool // the currency and the amount are separated by the space
+}
+
+// currencyPatterns are the currency patterns of the locales differing from "¤#,##0.00".
+var currencyPatterns = map[string]currencyPattern{
+	"az": {suffix: true, space: true}, "be": {suffix: true, space: true}, "bg": {suffix: true, space: true},
+	"bs": {suffix: true, space: true}, "ca": {suffix: true, space: true}, "cs": {suffix: true, space: true},
+	"da": {suffix: true, space: true}, "de": {suffix: true, space: true}, "el": {suffix: true, space: true},
+	"es": {suffix: true, space: true}, "et": {suffix: true, space: true}, "eu": {suffix: true, space: true},
+	"fi": {suffix: true, space: true}, "fr": {suffix: true, space: true}, "gl": {suffix: true, space: true},
+	"hr": {suffix: true, space: true}, "hu": {suffix: true, space: true}, "hy": {suffix: true, space: true},
+	"is": {suffix: true, space: true}, "it": {suffix: true, space: true}, "ka": {suffix: true, space: true},
+	"kk": {suffix: true, space: true}, "lt": {suffix: true, space: true}, "lv": {suffix: true, space: true},
+	"mk": {suffix: true, space: true}, "nb": {suffix: true, space: true}, "no": {suffix: true, space: true},
+	"pl": {suffix: true, space: true}, "pt-PT": {suffix: true, space: true}, "ro": {suffix: true, space: true},
+	"ru": {suffix: true, space: true}, "sk": {suffix: true, space: true}, "sl": {suffix: true, space: true},
+	"sq": {suffix: true, space: true}, "sr": {suffix: true, space: true}, "sv": {suffix: true, space: true},
+	"uk": {suffix: true, space: true}, "uz": {suffix: true, space: true}, "vi": {suffix: true, space: true},
+	"de-AT": {space: true}, "de-CH": {space: true}, "it-CH": {space: true}, "nl": {space: true},
+	"pt": {space: true}, "es-419": {}, "es-MX": {}, "es-US": {},
+}
+
+// currencyPatternOf returns the currency pattern of the locale or its closest parent.
+func currencyPatternOf(locale language.Tag) currencyPattern {
+	base, _ := locale.Base()
+	region, _ := locale.Region()
+
+	tag, err := language.Compose(base, region)
+	if err != nil {
+		return currencyPattern{}
+	}
+
+	for ; tag != language.Und; tag = tag.Parent() {
+		if pattern, ok := currencyPatterns[tag.String()]; ok {
+			return pattern
+		}
+	}
+
+	return currencyPattern{}
+}
+
+// format places the currency before or after the formatted amount. As in CLDR currency spacing,
+// the no-break space separates the amount from the currency ending with a letter, e.g. "USD 42.00".
+func (p currencyPattern) format(curr, amount string) string {
+	if p.suffix {
+		r, _ := utf8.DecodeRuneInString(curr)
+		if p.space || !unicode.IsSymbol(r) {
+			return amount + "\u00a0" + curr
+		}
+
+		return amount + curr
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(curr)
+	if p.space || !unicode.IsSymbol(r) {
+		return curr + "\u00a0" + amount
+	}
+
+	return curr + amount
+}
+
+// withSign adds the sign to the formatted absolute value according to the signDisplay option.
+func withSign(result string, value float64, signDisplay string) string {
+	switch signDisplay {
+	default: // auto, negative
+		if value < 0 {
+			return "-" + result
+		}
+	case "always":
+		if value < 0 {
+			return "-" + result
+		}
+
+		return "+" + result
+	case "exceptZero":
+		if value < 0 {
+			return "-" + result
+		}
+
+		if value > 0 {
+			return "+" + result
+		}
+	case "never":
+	}
+
+	return result
+}
@@ -0,0 +1,106 @@
+package template
+
+import (
+	"errors"
+	"testing"
+
+	"go.expect.digital/mf2"
+	"golang.org/x/text/language"
+)
+
+func Test_Currency(t *testing.T) {
+	t.Parallel()
+
+	assert := assertFormat(t, currencyFunc, map[string]any{"currency": "EUR"}, language.AmericanEnglish)
+	assert(42, "€42.00")
+	assert(-42, "-€42.00")
+	assert(0.126, "€0.13")
+	assert("1234.5", "€1,234.50")
+
+	assert = assertFormat(t, currencyFunc, map[string]any{"currency": "JPY"}, language.AmericanEnglish)
+	assert(42.6, "¥43")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "USD", "currencyDisplay": "code"}, language.AmericanEnglish)
+	assert(42, "USD\u00a042.00")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "USD", "currencyDisplay": "narrowSymbol"}, language.Latvian)
+	assert(42, "42,00\u00a0$")
+
+	assert = assertFormat(t, currencyFunc, map[string]any{"currency": "EUR"}, language.German)
+	assert(-1234.5, "-1.234,50\u00a0€")
+
+	assert = assertFormat(t, currencyFunc, map[string]any{"currency": "CHF"}, language.MustParse("de-CH"))
+	assert(42, "CHF\u00a042.00")
+
+	assert = assertFormat(t, currencyFunc, map[string]any{"currency": "EUR"}, language.Dutch)
+	assert(42, "€\u00a042,00")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "EUR", "currencySign": "accounting"}, language.AmericanEnglish)
+	assert(-42, "(€42.00)")
+	assert(42, "€42.00")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "EUR", "signDisplay": "exceptZero"}, language.AmericanEnglish)
+	assert(42, "+€42.00")
+	assert(0, "€0.00")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "EUR", "fractionDigits": "auto"}, language.AmericanEnglish)
+	assert(42, "€42.00")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "EUR", "fractionDigits": "0"}, language.AmericanEnglish)
+	assert(42.4, "€42")
+
+	assert = assertFormat(t, currencyFunc,
+		map[string]any{"currency": "EUR", "fractionDigits": 3}, language.AmericanEnglish)
+	assert(42, "€42.000")
+}
+
+func Test_CurrencyErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		input   any
+		options Options
+		want    error
+	}{
+		{"missing operand", nil, Options{"currency": NewResolvedValue("EUR")}, mf2.ErrBadOperand},
+		{"bad operand", "foo", Options{"currency": NewResolvedValue("EUR")}, mf2.ErrBadOperand},
+		{"missing currency", 42, nil, mf2.ErrBadOption},
+		{"bad currency", 42, Options{"currency": NewResolvedValue("ABCD")}, mf2.ErrBadOption},
+		{
+			"unsupported option", 42,
+			Options{"currency": NewResolvedValue("EUR"), "foo": NewResolvedValue("bar")},
+			mf2.ErrBadOption,
+		},
+		{
+			"name display", 42,
+			Options{"currency": NewResolvedValue("EUR"), "currencyDisplay": NewResolvedValue("name")},
+			mf2.ErrBadOption,
+		},
+		{
+			"bad fraction digits type", 42,
+			Options{"currency": NewResolvedValue("EUR"), "fractionDigits": NewResolvedValue(true)},
+			mf2.ErrBadOption,
+		},
+		{
+			"bad fraction digits", 42,
+			Options{"currency": NewResolvedValue("EUR"), "fractionDigits": NewResolvedValue("foo")},
+			mf2.ErrBadOption,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := currencyFunc(NewResolvedValue(test.input), test.options, language.AmericanEnglish)
+			if !errors.Is(err, test.want) {
+				t.Errorf("want '%s', got '%s'", test.want, err)
+			}
+		})
+	}
+}
@@ -75,9 +75,7 @@ func dateFunc(operand *ResolvedValue, options Options, _ language.Tag) (*Resolve
 			layout = "02/01/06"
 		}
 
-		value = value.In(opts.TimeZone)
-
-		return value.Format(layout)
+		return value.In(opts.TimeZone).Format(layout)
 	}
 
 	return NewResolvedValue(value, WithFormat(format)), nil
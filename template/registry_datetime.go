@@ -225,15 +225,17 @@ func datetimeFunc(operand *ResolvedValue, options Options, locale language.Tag)
 	format := func() string {
 		var layout string
 
+		// the captured value is not modified, the format can be called concurrently
+		t := value
 		if opts.TimeZone != nil {
-			value = value.In(opts.TimeZone)
+			t = value.In(opts.TimeZone)
 		}
 
 		if opts.Year != "" || opts.Day != "" {
 			return intl.NewDateTimeFormat(locale, intl.Options{
 				Year: intl.MustParseYear(opts.Year),
 				Day:  intl.MustParseDay(opts.Day),
-			}).Format(value)
+			}).Format(t)
 		}
 
 		switch opts.DateStyle {
@@ -264,7 +266,7 @@ func datetimeFunc(operand *ResolvedValue, options Options, locale language.Tag)
 			}
 		}
 
-		return value.Format(layout)
+		return t.Format(layout)
 	}
 
 	return NewResolvedValue(value, WithFormat(format)), nil
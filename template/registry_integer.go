@@ -8,16 +8,17 @@ import (
 
 // integerFunc is the implementation of the integer function. Locale-sensitive integer formatting.
 func integerFunc(operand *ResolvedValue, options Options, locale language.Tag) (*ResolvedValue, error) {
-	if options == nil {
-		options = Options{"maximumFractionDigits": NewResolvedValue(0)}
-	} else {
-		options["maximumFractionDigits"] = NewResolvedValue(0)
-	}
+	options = mergeOptions(options, Options{"maximumFractionDigits": NewResolvedValue(0)})
 
+	// The options of the previous numeric annotation are inherited by the number function.
 	value, err := numberFunc(operand, options, locale)
 	if err != nil {
-		return nil, fmt.Errorf("exec integer func: %w", err)
+		err = fmt.Errorf("exec integer func: %w", err)
+	}
+
+	if value == nil {
+		return nil, err
 	}
 
-	return value, nil
+	return NewResolvedValue(value, withFunction(":integer", value.options)), err
 }
@@ -3,12 +3,12 @@ package template
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 
 	"go.expect.digital/mf2"
 	"golang.org/x/text/currency"
 	"golang.org/x/text/feature/plural"
 	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 	"golang.org/x/text/number"
 )
 
@@ -123,6 +123,8 @@ type numberOptions struct {
 	MaximumSignificantDigits int
 }
 
+// parseNumberOptions parses :number options. If the "select" option is not set by a literal,
+// the options are returned together with [mf2.ErrBadOption].
 func parseNumberOptions(opts Options) (*numberOptions, error) {
 	errorf := func(format string, args ...any) (*numberOptions, error) {
 		return nil, fmt.Errorf("%w: "+format, append([]any{mf2.ErrBadOption}, args...)...)
@@ -145,9 +147,17 @@ func parseNumberOptions(opts Options) (*numberOptions, error) {
 
 	selects := oneOf("plural", "ordinal", "exact")
 
-	options.Select, err = opts.GetString("select", "plural", selects)
-	if err != nil {
-		return errorf("%w", err)
+	// The select option must be set by a literal, otherwise the default is used.
+	var selectErr error
+
+	if v, ok := opts["select"]; ok && v.fromVariable {
+		options.Select = "plural"
+		selectErr = fmt.Errorf(`%w: option "select" must be set by a literal`, mf2.ErrBadOption)
+	} else {
+		options.Select, err = opts.GetString("select", "plural", selects)
+		if err != nil {
+			return errorf("%w", err)
+		}
 	}
 
 	useGroupings := oneOf("auto", "always", "never", "min2")
@@ -270,7 +280,7 @@ func parseNumberOptions(opts Options) (*numberOptions, error) {
 		return errorf("%w", err)
 	}
 
-	return &options, nil
+	return &options, selectErr
 }
 
 // numberFunc is the implementation of the number function. Locale-sensitive number formatting.
@@ -284,12 +294,30 @@ func numberFunc(operand *ResolvedValue, options Options, locale language.Tag) (*
 		return errorf("%w", err)
 	}
 
-	opts, err := parseNumberOptions(options)
-	if err != nil {
-		return errorf("%w", err)
+	// The options of the previous numeric annotation are inherited,
+	// options of the current annotation take priority.
+	options = inheritOptions(operand, options, ":number", ":integer", ":percent")
+
+	// The bad "select" option is replaced with the default, the value is still usable.
+	opts, selectErr := parseNumberOptions(options)
+	if opts == nil {
+		return errorf("%w", selectErr)
 	}
 
-	p := message.NewPrinter(locale)
+	// x/text/number does not support minimum significant digits,
+	// the missing digits are displayed as fraction digits instead.
+	if _, ok := options["minimumSignificantDigits"]; ok {
+		scaled := value
+		if opts.Style == "percent" {
+			scaled *= 100
+		}
+
+		opts.MinimumFractionDigits = max(opts.MinimumFractionDigits,
+			significantFractionDigits(scaled, opts.MinimumSignificantDigits))
+		opts.MaximumFractionDigits = max(opts.MaximumFractionDigits, opts.MinimumFractionDigits)
+	}
+
+	p := newPrinter(locale)
 	numberOpts := []number.Option{
 		number.MinFractionDigits(opts.MinimumFractionDigits),
 		number.MaxFractionDigits(opts.MaximumFractionDigits),
@@ -332,8 +360,22 @@ func numberFunc(operand *ResolvedValue, options Options, locale language.Tag) (*
 	}
 
 	selectKey := func(keys []string) string {
-		if hasExactKey(keys) {
-			return format()
+		exact := value
+		if opts.Style == "percent" {
+			exact *= 100
+		}
+
+		if key, ok := exactKey(keys, round(exact, opts.MaximumFractionDigits)); ok {
+			return key
+		}
+
+		if opts.Select == "exact" {
+			return ""
+		}
+
+		rules := plural.Cardinal
+		if opts.Select == "ordinal" {
+			rules = plural.Ordinal
 		}
 
 		scale := -1
@@ -343,23 +385,50 @@ func numberFunc(operand *ResolvedValue, options Options, locale language.Tag) (*
 		}
 
 		digits := num.Digits(nil, locale, scale)
-		form := plural.Cardinal.MatchDigits(locale, digits.Digits, int(digits.Exp), int(digits.End-digits.Exp))
+		form := rules.MatchDigits(locale, digits.Digits, int(digits.Exp), int(digits.End-digits.Exp))
 
 		return pluralFormString(form)
 	}
 
-	return NewResolvedValue(value, WithFormat(format), WithSelectKey(selectKey)), nil
+	result := NewResolvedValue(value,
+		WithFormat(format), WithSelectKey(selectKey), withFunction(":number", options), withDir(locale))
+	if selectErr != nil {
+		return result, fmt.Errorf("exec number function: %w", selectErr)
+	}
+
+	return result, nil
 }
 
-// hasExactKey returns true if the variant keys contain exact value besides the plural categories.
-func hasExactKey(keys []string) bool {
+// significantFractionDigits returns the number of fraction digits required
+// to display at least n significant digits of the value.
+func significantFractionDigits(value float64, n int) int {
+	if value == 0 {
+		return 0
+	}
+
+	// position of the most significant digit, e.g. 1 for 12.3, -1 for 0.05
+	exp := int(math.Floor(math.Log10(math.Abs(value)))) + 1
+
+	return max(0, n-exp)
+}
+
+// exactKey returns the first key that is numerically equal to the value.
+func exactKey(keys []string, value float64) (string, bool) {
 	for _, key := range keys {
-		switch key {
-		default:
-			return true
-		case "zero", "one", "two", "few", "many", "other": // check next key
+		var n float64
+
+		err := json.Unmarshal([]byte(key), &n)
+		if err == nil && n == value {
+			return key, true
 		}
 	}
 
-	return false
+	return "", false
+}
+
+// round rounds the value half to even to the given number of fraction digits.
+func round(value float64, digits int) float64 {
+	p := math.Pow10(digits)
+
+	return math.RoundToEven(value*p) / p
 }
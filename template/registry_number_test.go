@@ -1,8 +1,10 @@
 package template
 
 import (
+	"errors"
 	"testing"
 
+	"go.expect.digital/mf2"
 	"golang.org/x/text/language"
 )
 
@@ -105,3 +107,128 @@ func Test_Number(t *testing.T) {
 	assert = assertFormat(t, numberFunc, map[string]any{}, language.Latvian)
 	assert("0.1", "0,1")
 }
+
+func Test_NumberInheritOptions(t *testing.T) {
+	t.Parallel()
+
+	resolve := func(f Func, operand *ResolvedValue, options map[string]any) *ResolvedValue {
+		t.Helper()
+
+		opts := make(Options, len(options))
+		for k, v := range options {
+			opts[k] = NewResolvedValue(v)
+		}
+
+		v, err := f(operand, opts, language.AmericanEnglish)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return v
+	}
+
+	for _, test := range []struct {
+		name string
+		got  *ResolvedValue
+		want string
+	}{
+		{
+			name: "integer to number",
+			got:  resolve(numberFunc, resolve(integerFunc, NewResolvedValue(1.25), nil), nil),
+			want: "1",
+		},
+		{
+			name: "number to integer",
+			got: resolve(integerFunc,
+				resolve(numberFunc, NewResolvedValue(1), map[string]any{"signDisplay": "always"}), nil),
+			want: "+1",
+		},
+		{
+			name: "number to number, override",
+			got: resolve(numberFunc,
+				resolve(numberFunc, NewResolvedValue(1.2345),
+					map[string]any{"maximumFractionDigits": 1, "signDisplay": "always"}),
+				map[string]any{"maximumFractionDigits": 2}),
+			want: "+1.23",
+		},
+		{
+			name: "string is not inherited",
+			got:  resolve(numberFunc, resolve(stringFunc, NewResolvedValue("1.2345"), nil), nil),
+			want: "1.234",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.got.String(); test.want != got {
+				t.Errorf("want '%s', got '%s'", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_NumberSelect(t *testing.T) {
+	t.Parallel()
+
+	literal := NewResolvedValue
+	variable := func(v any) *ResolvedValue {
+		r := NewResolvedValue(v)
+		r.fromVariable = true
+
+		return r
+	}
+
+	exact, err := numberFunc(NewResolvedValue(1), Options{"select": literal("exact")}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		operand *ResolvedValue
+		options Options
+		keys    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "plural", operand: NewResolvedValue(2), keys: []string{"1", "one", "other"}, want: "other"},
+		{name: "plural exact", operand: NewResolvedValue(1), keys: []string{"1", "one"}, want: "1"},
+		{
+			name:    "exact",
+			operand: NewResolvedValue(1),
+			options: Options{"select": literal("exact")},
+			keys:    []string{"one"},
+			want:    "",
+		},
+		{
+			name:    "ordinal",
+			operand: NewResolvedValue(2),
+			options: Options{"select": literal("ordinal")},
+			keys:    []string{"one", "two"},
+			want:    "two",
+		},
+		{
+			name:    "variable",
+			operand: NewResolvedValue(1),
+			options: Options{"select": variable("exact")},
+			keys:    []string{"one"},
+			want:    "one",
+			wantErr: true,
+		},
+		{name: "inherited", operand: exact, keys: []string{"one"}, want: ""},
+		{name: "inherited exact", operand: exact, keys: []string{"1", "one"}, want: "1"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			v, err := numberFunc(test.operand, test.options, language.AmericanEnglish)
+			if test.wantErr != errors.Is(err, mf2.ErrBadOption) {
+				t.Errorf("want bad option error %t, got %v", test.wantErr, err)
+			}
+
+			if got := v.selectKey(test.keys); got != test.want {
+				t.Errorf("want '%s', got '%s'", test.want, got)
+			}
+		})
+	}
+}
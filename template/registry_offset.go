@@ -0,0 +1,80 @@
+package template
+
+import (
+	"fmt"
+
+	"go.expect.digital/mf2"
+	"golang.org/x/text/language"
+)
+
+// parseOffsetOptions parses :offset options and returns the offset to add to the operand.
+// Exactly one of the options "add" or "subtract" is required, other options are ignored.
+func parseOffsetOptions(options Options) (int, error) {
+	errorf := func(format string, args ...any) (int, error) {
+		return 0, fmt.Errorf("%w: "+format, append([]any{mf2.ErrBadOption}, args...)...)
+	}
+
+	_, hasAdd := options["add"]
+	_, hasSubtract := options["subtract"]
+
+	switch {
+	case hasAdd && hasSubtract:
+		return errorf(`want either "add" or "subtract", got both`)
+	case hasAdd:
+		n, err := options.GetInt("add", 0, eqOrGreaterThan(0))
+		if err != nil {
+			return errorf("%w", err)
+		}
+
+		return n, nil
+	case hasSubtract:
+		n, err := options.GetInt("subtract", 0, eqOrGreaterThan(0))
+		if err != nil {
+			return errorf("%w", err)
+		}
+
+		return -n, nil
+	default:
+		return errorf(`want "add" or "subtract"`)
+	}
+}
+
+// offsetFunc is the implementation of the offset function. It adds or subtracts an integer
+// from the numeric operand. The result is formatted and selected with the function
+// and options of the operand, e.g. ":integer signDisplay=always".
+func offsetFunc(operand *ResolvedValue, options Options, locale language.Tag) (*ResolvedValue, error) {
+	errorf := func(format string, args ...any) (*ResolvedValue, error) {
+		return nil, fmt.Errorf("exec offset function: "+format, args...)
+	}
+
+	value, err := parseNumberOperand(operand)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	offset, err := parseOffsetOptions(options)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	var (
+		f         Func = numberFunc
+		inherited Options
+	)
+
+	switch operand.function {
+	case ":number":
+		inherited = operand.options
+	case ":integer":
+		f, inherited = integerFunc, operand.options
+	case ":percent":
+		f, inherited = percentFunc, operand.options
+	}
+
+	result, err := f(NewResolvedValue(value+float64(offset)), mergeOptions(inherited, nil), locale)
+	if err != nil {
+		return errorf("%w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package template
+
+import (
+	"errors"
+	"testing"
+
+	"go.expect.digital/mf2"
+	"golang.org/x/text/language"
+)
+
+func Test_Offset(t *testing.T) {
+	t.Parallel()
+
+	assert := assertFormat(t, offsetFunc, map[string]any{"add": 1}, language.AmericanEnglish)
+	assert(41, "42")
+	assert("999", "1,000")
+
+	assert = assertFormat(t, offsetFunc, map[string]any{"subtract": "10"}, language.AmericanEnglish)
+	assert(52, "42")
+	assert(0.5, "-9.5")
+
+	// operand options are preserved
+	operand, err := integerFunc(NewResolvedValue(41),
+		Options{"signDisplay": NewResolvedValue("always")}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := offsetFunc(operand, Options{"add": NewResolvedValue(1)}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "+42", v.String(); want != got {
+		t.Errorf("want '%s', got '%s'", want, got)
+	}
+
+	if want, got := "42", v.selectKey([]string{"41", "42", "one"}); want != got {
+		t.Errorf("want key '%s', got '%s'", want, got)
+	}
+}
+
+func Test_OffsetErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		input   any
+		options map[string]any
+		want    error
+	}{
+		{"missing operand", nil, map[string]any{"add": 1}, mf2.ErrBadOperand},
+		{"bad operand", "foo", map[string]any{"add": 1}, mf2.ErrBadOperand},
+		{"missing options", 42, nil, mf2.ErrBadOption},
+		{"unknown option only", 42, map[string]any{"foo": 13}, mf2.ErrBadOption},
+		{"add and subtract", 42, map[string]any{"add": 13, "subtract": 13}, mf2.ErrBadOption},
+		{"bad add", 42, map[string]any{"add": "foo"}, mf2.ErrBadOption},
+		{"negative subtract", 42, map[string]any{"subtract": -1}, mf2.ErrBadOption},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := make(Options, len(test.options))
+			for k, v := range test.options {
+				opts[k] = NewResolvedValue(v)
+			}
+
+			_, err := offsetFunc(NewResolvedValue(test.input), opts, language.AmericanEnglish)
+			if !errors.Is(err, test.want) {
+				t.Errorf("want '%s', got '%s'", test.want, err)
+			}
+		})
+	}
+}
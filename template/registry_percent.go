@@ -0,0 +1,30 @@
+package template
+
+import (
+	"fmt"
+	"maps"
+
+	"golang.org/x/text/language"
+)
+
+// percentFunc is the implementation of the percent function. Locale-sensitive percent formatting.
+// The value is multiplied by 100 for formatting and selection.
+func percentFunc(operand *ResolvedValue, options Options, locale language.Tag) (*ResolvedValue, error) {
+	opts := mergeOptions(options, Options{"style": NewResolvedValue("percent")})
+
+	// The options of the previous numeric annotation are inherited by the number function.
+	value, err := numberFunc(operand, opts, locale)
+	if err != nil {
+		err = fmt.Errorf("exec percent function: %w", err)
+	}
+
+	if value == nil {
+		return nil, err
+	}
+
+	// the style is implied by the function
+	options = maps.Clone(value.options)
+	delete(options, "style")
+
+	return NewResolvedValue(value, withFunction(":percent", options)), err
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func Test_Percent(t *testing.T) {
+	t.Parallel()
+
+	assert := assertFormat(t, percentFunc, nil, language.AmericanEnglish)
+	assert(1, "100%")
+	assert(0.12345678, "12%")
+	assert(-0.5, "-50%")
+	assert("0.01", "1%")
+
+	assert = assertFormat(t, percentFunc, map[string]any{"maximumFractionDigits": 1}, language.Latvian)
+	assert(0.12345678, "12,3%")
+
+	assert = assertFormat(t, percentFunc, map[string]any{"minimumFractionDigits": 1}, language.AmericanEnglish)
+	assert(0.12, "12.0%")
+
+	assert = assertFormat(t, percentFunc, map[string]any{"minimumSignificantDigits": 3}, language.AmericanEnglish)
+	assert(0.12, "12.0%")
+	assert(0.005, "0.500%")
+
+	assert = assertFormat(t, percentFunc, map[string]any{"maximumSignificantDigits": 2}, language.AmericanEnglish)
+	assert(0.12345, "12%")
+
+	assert = assertFormat(t, percentFunc, map[string]any{"signDisplay": "always"}, language.AmericanEnglish)
+	assert(0.1, "+10%")
+}
+
+func Test_PercentSelect(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		input any
+		want  string
+	}{
+		{0.01, "one"},
+		{1, "other"},
+		{0.02, "other"},
+	} {
+		v, err := percentFunc(NewResolvedValue(test.input), nil, language.AmericanEnglish)
+		if err != nil {
+			t.Error(err)
+
+			continue
+		}
+
+		if got := v.selectKey([]string{"one"}); got != test.want {
+			t.Errorf("want '%s' for %v, got '%s'", test.want, test.input, got)
+		}
+	}
+}
+
+func Test_PercentInheritOptions(t *testing.T) {
+	t.Parallel()
+
+	number, err := numberFunc(NewResolvedValue(0.12346),
+		Options{"maximumFractionDigits": NewResolvedValue(2)}, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := percentFunc(number, nil, language.AmericanEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "12.35%", v.String(); want != got {
+		t.Errorf("want '%s', got '%s'", want, got)
+	}
+}
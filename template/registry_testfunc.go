@@ -2,7 +2,6 @@ package template
 
 import (
 	"fmt"
-	"maps"
 	"math"
 	"strconv"
 
@@ -32,12 +31,7 @@ func RegistryTestFunc(name string) func(*ResolvedValue, Options, language.Tag) (
 
 		// "If arg is the resolved value of an expression with a :test:function, :test:select, or :test:format
 		// annotation for which resolution has succeeded, then [..]" merge all options from resolved value.
-		switch operand.function {
-		case ":test:function", ":test:format", ":test:select":
-			merged := maps.Clone(operand.options)
-			maps.Copy(merged, options)
-			options = merged
-		}
+		options = inheritOptions(operand, options, ":test:function", ":test:format", ":test:select")
 
 		opts, err := parseTestFunctionOptions(options)
 		if err != nil {
@@ -77,9 +77,7 @@ func timeFunc(operand *ResolvedValue, options Options, _ language.Tag) (*Resolve
 			layout = "15:04"
 		}
 
-		value = value.In(opts.TimeZone)
-
-		return value.Format(layout)
+		return value.In(opts.TimeZone).Format(layout)
 	}
 
 	return NewResolvedValue(value, WithFormat(format)), nil
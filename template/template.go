@@ -1,9 +1,12 @@
 package template
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"maps"
 	"slices"
 	"sort"
@@ -18,10 +21,20 @@ import (
 )
 
 // Template represents a MessageFormat2 template.
+//
+// Once parsed, the template is safe for concurrent use by multiple goroutines, i.e. [Template.Execute],
+// [Template.Sprint] and [Template.FormatToParts] can be called concurrently. The template must not be parsed again
+// while it is executed.
 type Template struct {
-	ast      *ast.AST
-	registry Registry
-	locale   language.Tag
+	ast           *ast.AST
+	plan          *plan
+	registry      Registry
+	contextFuncs  map[string]ContextFunc
+	customFuncs   map[string]bool // the functions added by [WithFunc] and [WithFuncs]
+	markupHandler MarkupHandler
+	logger        *slog.Logger
+	locale        language.Tag
+	bidiIsolation BidiIsolation
 }
 
 // ResolvedValue keeps the result of the Expression resolution with optionally
@@ -33,13 +46,30 @@ type ResolvedValue struct {
 	options   Options
 	err       error
 	function  string
+	// locale is the locale of the expression.
+	locale language.Tag
+	// id is the value of u:id option.
+	id string
+	// dir is the direction of the formatted value (ltr, rtl). Empty if unknown.
+	dir string
+	// fromVariable is true if the value is an option value set by a variable.
+	fromVariable bool
+	// isolate is true if the direction is set by u:dir option, the value is always isolated.
+	isolate bool
+	// fallback is true if the value is a fallback value, e.g. "{$var}".
+	fallback bool
 }
 
 // NewResolvedValue creates a new variable of type [*ResolvedValue].
-// If value is already [*ResolvedValue], the optional format() and selectKey() are applied to it.
+// If value is already [*ResolvedValue], the optional format() and selectKey() are applied
+// to its copy, the value itself is not modified.
 func NewResolvedValue(value any, options ...ResolvedValueOpt) *ResolvedValue {
-	resolved, ok := value.(*ResolvedValue)
-	if !ok {
+	var resolved *ResolvedValue
+
+	if r, ok := value.(*ResolvedValue); ok {
+		c := *r
+		resolved = &c
+	} else {
 		resolved = &ResolvedValue{value: value}
 	}
 
@@ -116,6 +146,7 @@ func WithFormat(format func() string) ResolvedValueOpt {
 // The selection function is called in the selection context.
 //
 // Keys exclude catch all key "*". If keys contain "*", it is string literal and is NOT catch all key.
+// The keys are shared by the executions of the template and must not be modified.
 func WithSelectKey(selectKey func(keys []string) string) ResolvedValueOpt {
 	return func(r *ResolvedValue) {
 		r.selectKey = selectKey
@@ -131,35 +162,63 @@ func withFunction(funcName string, opts Options) ResolvedValueOpt {
 	}
 }
 
-func newFallbackValue(expr ast.Expression) *ResolvedValue {
-	wrap := func(v string) *ResolvedValue {
-		return NewResolvedValue("{" + v + "}")
+// withDir applies the direction of the locale to the [ResolvedValue].
+func withDir(locale language.Tag) ResolvedValueOpt {
+	return func(r *ResolvedValue) {
+		r.dir = localeDir(locale)
+	}
+}
+
+// localeDir returns the direction of the locale's script (ltr, rtl).
+func localeDir(locale language.Tag) string {
+	script, _ := locale.Script()
+
+	switch script.String() {
+	default:
+		return "ltr"
+	case "Adlm", "Arab", "Hebr", "Mand", "Mend", "Nkoo", "Rohg", "Samr", "Syrc", "Thaa":
+		return "rtl"
 	}
+}
+
+// newFallback creates a fallback value for the source, e.g. "{$var}" for "$var".
+func newFallback(source string) *ResolvedValue {
+	return &ResolvedValue{value: "{" + source + "}", fallback: true}
+}
 
+func newFallbackValue(expr ast.Expression) *ResolvedValue {
+	return newFallback(exprSource(expr))
+}
+
+// exprSource returns the source of the expression used in the fallback value and the formatted parts.
+func exprSource(expr ast.Expression) string {
 	switch v := expr.Operand.(type) {
 	default:
-		return wrap("\ufffd") // the U+FFFD REPLACEMENT CHARACTER �
+		return "\ufffd" // the U+FFFD REPLACEMENT CHARACTER �
 	case nil:
 		switch f := expr.Annotation.(type) {
 		default:
-			return wrap(f.String())
+			return f.String()
 		case ast.Function:
-			return wrap(":" + f.Identifier.String())
+			return ":" + f.Identifier.String()
 		}
 	case ast.QuotedLiteral:
-		return wrap(v.String())
+		return v.String()
 	case ast.NameLiteral:
-		return wrap(ast.QuotedLiteral(v).String())
+		return ast.QuotedLiteral(v).String()
 	case ast.Variable:
-		return wrap(v.String())
+		return v.String()
 	}
 }
 
 // New returns a new Template.
 func New(options ...Option) *Template {
 	t := &Template{
-		registry: NewRegistry(),
-		locale:   language.AmericanEnglish,
+		registry:     NewRegistry(),
+		contextFuncs: make(map[string]ContextFunc),
+		customFuncs:  make(map[string]bool),
+		logger:       slog.New(slog.DiscardHandler),
+		locale:       language.AmericanEnglish,
 	}
 
 	for _, o := range options {
@@ -181,6 +240,8 @@ type Option func(t *Template)
 func WithFunc(name string, f Func) Option {
 	return func(t *Template) {
 		t.registry[name] = f
+		t.customFuncs[name] = true
+		delete(t.contextFuncs, name)
 	}
 }
 
@@ -195,6 +256,38 @@ func WithFunc(name string, f Func) Option {
 func WithFuncs(reg Registry) Option {
 	return func(t *Template) {
 		maps.Copy(t.registry, reg)
+
+		for name := range reg {
+			t.customFuncs[name] = true
+			delete(t.contextFuncs, name)
+		}
+	}
+}
+
+// WithContextFunc adds a single function with the context of the call to function registry,
+// it replaces the function of [WithFunc] with the same name.
+//
+// Example:
+//
+//	WithContextFunc("user:name", func(ctx context.Context, fc FuncContext, input *ResolvedValue, options Options) (*ResolvedValue, error) {
+//		name, err := users.Name(ctx, input.String())
+//		if err != nil {
+//			return nil, fmt.Errorf("look up user name: %w", err)
+//		}
+//
+//		return NewResolvedValue(name), nil
+//	})
+func WithContextFunc(name string, f ContextFunc) Option {
+	return func(t *Template) {
+		t.contextFuncs[name] = f
+		delete(t.registry, name)
+	}
+}
+
+// WithLogger sets the logger passed to the functions in [FuncContext]. The logs are discarded by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Template) {
+		t.logger = logger
 	}
 }
 
@@ -205,7 +298,29 @@ func WithLocale(locale language.Tag) Option {
 	}
 }
 
-// Parse parses the MessageFormat2 string and returns the template.
+// BidiIsolation is the strategy for isolating the placeholders in the formatted message.
+type BidiIsolation int
+
+const (
+	// BidiIsolationNone does not isolate the placeholders.
+	BidiIsolationNone BidiIsolation = iota
+	// BidiIsolationDefault wraps the placeholders in U+2068 FSI, U+2066 LRI or U+2067 RLI
+	// and U+2069 PDI according to the direction of the formatted value and the message.
+	//
+	// See ".message-format-wg/spec/formatting.md#handling-bidirectional-text".
+	BidiIsolationDefault
+)
+
+// WithBidiIsolation sets the bidi isolation strategy, [BidiIsolationNone] by default.
+func WithBidiIsolation(strategy BidiIsolation) Option {
+	return func(t *Template) {
+		t.bidiIsolation = strategy
+	}
+}
+
+// Parse parses the MessageFormat2 string and returns the template. The message is compiled once,
+// the functions are looked up, the literal options are resolved and the variant keys are collected,
+// and the expressions without variables are resolved by the default functions.
 func (t *Template) Parse(input string) (*Template, error) {
 	ast, err := ast.Parse(input)
 	if err != nil {
@@ -213,17 +328,77 @@ func (t *Template) Parse(input string) (*Template, error) {
 	}
 
 	t.ast = &ast
+	t.plan = t.compile(ast.Message)
 
 	return t, nil
 }
 
+// Bind returns a copy of the template for the locale, e.g. the locale of the request. The copy shares
+// the parsed message and the function registry with the template, the message is not parsed again.
+//
+// Example:
+//
+//	t, _ := template.New().Parse("{$n :number}")
+//
+//	s, _ := t.Bind(language.Latvian).Sprint(map[string]any{"n": 1.5}) // 1,5
+func (t *Template) Bind(locale language.Tag) *Template {
+	bound := *t
+	bound.locale = locale
+
+	return &bound
+}
+
+// Inputs returns the names of the input variables the message requires, without the "$" prefix.
+// It returns nil if the template is not parsed. See [ast.Analyze].
+func (t *Template) Inputs() []string {
+	if t.ast == nil {
+		return nil
+	}
+
+	info := ast.Analyze(*t.ast)
+	inputs := make([]string, 0, len(info.Inputs))
+
+	for _, v := range info.Inputs {
+		inputs = append(inputs, string(v))
+	}
+
+	return inputs
+}
+
 // Execute writes the result of the template to the given writer.
+// The input map and the input values are not modified, they can be shared by concurrent executions.
 func (t *Template) Execute(w io.Writer, input map[string]any) error {
+	return t.ExecuteContext(context.Background(), w, input)
+}
+
+// ExecuteContext writes the result of the template to the given writer. The context is passed to
+// the functions of [WithContextFunc]. The execution stops with the context error when ctx is done.
+func (t *Template) ExecuteContext(ctx context.Context, w io.Writer, input map[string]any) error {
 	if t.ast == nil {
 		return errors.New("execute template: AST is nil")
 	}
 
-	executer := &executer{template: t, w: w, variables: make(map[string]*ResolvedValue, len(input))}
+	executer, err := t.newExecuter(ctx, w, input)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	err = executer.execute()
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	return nil
+}
+
+// newExecuter creates the executer of the template with the resolved input variables.
+func (t *Template) newExecuter(ctx context.Context, w io.Writer, input map[string]any) (*executer, error) {
+	executer := &executer{
+		ctx:       ctx,
+		template:  t,
+		w:         w,
+		variables: make(map[string]*ResolvedValue, len(input)),
+	}
 
 	for k, v := range input {
 		var f Func
@@ -240,18 +415,13 @@ func (t *Template) Execute(w io.Writer, input map[string]any) error {
 
 		resolved, err := f(NewResolvedValue(v), nil, t.locale)
 		if err != nil {
-			return fmt.Errorf("execute template: %w", err)
+			return nil, err
 		}
 
 		executer.variables[norm.NFC.String(k)] = resolved
 	}
 
-	err := executer.execute()
-	if err != nil {
-		return fmt.Errorf("execute template: %w", err)
-	}
-
-	return nil
+	return executer, nil
 }
 
 // Sprint wraps Execute and returns the result as a string.
@@ -263,41 +433,38 @@ func (t *Template) Sprint(input map[string]any) (string, error) {
 }
 
 type executer struct {
+	ctx       context.Context //nolint:containedctx // the context of the single execution
 	template  *Template
 	w         io.Writer
 	variables map[string]*ResolvedValue
+	parts     []Part
+	toParts   bool // append the formatted parts instead of writing to w
 }
 
 func (e *executer) execute() error {
-	switch message := e.template.ast.Message.(type) {
-	default: // this should never happen, AST must be valid.
-		return fmt.Errorf("unexpected message type: '%T'", message)
-	case nil:
-		return nil
-	case ast.SimpleMessage:
-		return e.resolvePattern(message)
-	case ast.ComplexMessage:
-		return e.resolveComplexMessage(message)
+	if p := e.template.plan; p.complex {
+		return e.resolveComplexMessage(p)
 	}
+
+	return e.resolvePattern(e.template.plan.pattern)
 }
 
-func (e *executer) resolveComplexMessage(message ast.ComplexMessage) error {
+func (e *executer) resolveComplexMessage(message *plan) error {
 	var resolutionErr error
 
-	err := e.resolveDeclarations(message.Declarations)
+	err := e.resolveDeclarations(message.declarations)
 
 	switch {
-	case errors.Is(err, mf2.ErrSyntax):
+	case errors.Is(err, mf2.ErrSyntax), e.ctx.Err() != nil:
 		return fmt.Errorf("complex message: %w", err)
 	case err != nil:
 		resolutionErr = fmt.Errorf("complex message: %w", err)
 	}
 
-	switch b := message.ComplexBody.(type) {
-	case ast.Matcher:
-		err = e.resolveMatcher(b)
-	case ast.QuotedPattern:
-		err = e.resolvePattern(b)
+	if message.matcher != nil {
+		err = e.resolveMatcher(message.matcher)
+	} else {
+		err = e.resolvePattern(message.pattern)
 	}
 
 	if err != nil {
@@ -307,31 +474,30 @@ func (e *executer) resolveComplexMessage(message ast.ComplexMessage) error {
 	return resolutionErr
 }
 
-func (e *executer) resolveDeclarations(declarations []ast.Declaration) error { //nolint:unparam
-	for _, decl := range declarations {
-		switch d := decl.(type) {
-		case ast.LocalDeclaration:
-			resolved, err := e.resolveExpression(d.Expression)
-			if err != nil {
-				resolved = NewResolvedValue("{" + d.Variable.String() + "}")
-				resolved.err = errors.Join(resolved.err, fmt.Errorf("resolve local %s: %w", d.Variable, err))
-			}
+func (e *executer) resolveDeclarations(declarations []declarationPlan) error {
+	for _, d := range declarations {
+		if err := e.ctx.Err(); err != nil {
+			return fmt.Errorf("declaration: %w", err)
+		}
 
-			e.variables[string(d.Variable)] = resolved
-		case ast.InputDeclaration:
-			resolved, err := e.resolveExpression(ast.Expression(d))
-			if err != nil {
-				resolved.err = errors.Join(resolved.err, fmt.Errorf("resolve input %s: %w", d.Operand, err))
-			}
+		resolved, err := e.resolveExpression(d.expr)
 
-			e.variables[string(d.Operand.(ast.Variable))] = resolved //nolint: forcetypeassert // always ast.Variable
+		switch {
+		case err == nil:
+		case d.input:
+			resolved.err = errors.Join(resolved.err, fmt.Errorf("resolve input %s: %w", ast.Variable(d.variable), err))
+		default:
+			resolved = newFallback(ast.Variable(d.variable).String())
+			resolved.err = errors.Join(resolved.err, fmt.Errorf("resolve local %s: %w", ast.Variable(d.variable), err))
 		}
+
+		e.variables[d.variable] = resolved
 	}
 
 	return nil
 }
 
-func (e *executer) resolvePattern(pattern []ast.PatternPart) error {
+func (e *executer) resolvePattern(pattern []partPlan) error {
 	var resolutionErr error
 
 	errorf := func(format string, args ...any) error {
@@ -339,83 +505,210 @@ func (e *executer) resolvePattern(pattern []ast.PatternPart) error {
 	}
 
 	for _, part := range pattern {
-		switch v := part.(type) {
+		if err := e.ctx.Err(); err != nil {
+			return errorf("%w", err)
+		}
+
+		switch v := part.part.(type) {
 		case ast.Text:
-			_, err := e.w.Write([]byte(v))
+			err := e.writeText(string(v))
 			if err != nil {
-				return errorf("write text: %w", err)
+				return errorf("%w", err)
 			}
 		case ast.Expression:
-			resolved, err := e.resolveExpression(v)
+			resolved, err := e.resolveExpression(part.expr)
+			if err == nil {
+				err = resolved.err
+			}
+
 			if err != nil {
 				resolutionErr = errors.Join(resolutionErr, err)
 			}
 
-			_, err = e.w.Write([]byte(resolved.String()))
+			err = e.writeExpression(v, resolved)
 			if err != nil {
-				return errorf("write resolved expression: %w", err)
+				return errorf("%w", err)
 			}
 		// When formatting to a string, markup placeholders format to an empty string by default.
 		// See ".message-format-wg/exploration/open-close-placeholders.md#formatting-to-a-string"
 		case ast.Markup:
+			if err := resolveMarkup(v); err != nil {
+				resolutionErr = errors.Join(resolutionErr, fmt.Errorf("pattern: %w", err))
+			}
+
+			switch {
+			case e.toParts:
+				err := e.appendMarkupPart(v)
+				if err != nil {
+					resolutionErr = errors.Join(resolutionErr, fmt.Errorf("pattern: %w", err))
+				}
+			case e.template.markupHandler != nil:
+				markup, err := e.resolveMarkupValue(v)
+				if err != nil {
+					resolutionErr = errors.Join(resolutionErr, fmt.Errorf("pattern: %w", err))
+					continue
+				}
+
+				if err := e.template.markupHandler.Markup(e.w, markup); err != nil {
+					return errorf("markup handler: %w", err)
+				}
+			}
 		}
 	}
 
 	return resolutionErr
 }
 
-func (e *executer) resolveExpression(expr ast.Expression) (*ResolvedValue, error) {
-	var (
-		funcName      string
-		options       Options
-		resolutionErr error
-	)
+// writeText writes the text, or appends it to the formatted parts.
+func (e *executer) writeText(text string) error {
+	if e.toParts {
+		e.parts = append(e.parts, Part{Type: PartText, Value: text})
+		return nil
+	}
+
+	if _, err := io.WriteString(e.w, e.escape(text)); err != nil {
+		return fmt.Errorf("write text: %w", err)
+	}
+
+	return nil
+}
+
+// writeExpression writes the formatted expression with the bidi isolation characters,
+// or appends them to the formatted parts.
+func (e *executer) writeExpression(expr ast.Expression, resolved *ResolvedValue) error {
+	start, end := e.isolates(resolved)
+
+	if e.toParts {
+		e.appendExpressionParts(expr, resolved, start, end)
+		return nil
+	}
+
+	if _, err := io.WriteString(e.w, start+e.escape(resolved.String())+end); err != nil {
+		return fmt.Errorf("write resolved expression: %w", err)
+	}
+
+	return nil
+}
+
+// escape escapes the text or the formatted expression if the markup handler requires it, see [HTMLMarkupHandler].
+func (e *executer) escape(s string) string {
+	if escaper, ok := e.template.markupHandler.(textEscaper); ok {
+		return escaper.escapeText(s)
+	}
+
+	return s
+}
+
+func (e *executer) resolveExpression(p *expressionPlan) (*ResolvedValue, error) {
+	// the expression without variables is resolved by [Template.compile]
+	if p.resolved != nil && p.locale == e.template.locale {
+		return p.resolved, nil
+	}
+
+	var resolutionErr error
+
+	expr := p.expr
 
 	value, err := e.resolveValue(expr.Operand)
 	if err != nil {
 		resolutionErr = errors.Join(resolutionErr, fmt.Errorf("expression: %w", err))
 	}
 
-	switch v := expr.Annotation.(type) {
-	default:
-		return newFallbackValue(expr), fmt.Errorf(`expression: %T annotation "%s": %w`, v, v, mf2.ErrUnsupportedExpression)
-	case ast.Function:
-		funcName = v.Identifier.String()
+	if p.err != nil {
+		return newFallbackValue(expr), p.err
+	}
+
+	options, u, uErr := p.options, p.u, p.uErr
+
+	// the plan is shared by the templates of the different locales, see [Template.Bind]
+	if u.locale == language.Und {
+		u.locale = e.template.locale
+	}
 
-		options, err = e.resolveOptions(v.Options)
+	if p.dynamic {
+		options, err = e.resolveOptions(expr.Annotation.(ast.Function).Options) //nolint:forcetypeassert // always ast.Function
 		if err != nil {
 			return newFallbackValue(expr), fmt.Errorf("expression: %w", err)
 		}
-	case nil: // noop, no annotation
+
+		u, uErr = resolveUOptions(options, e.template.locale)
+	}
+
+	// The literal options of the plan are shared by the concurrent executions,
+	// the functions other than the builtin ones get a copy they may modify.
+	if !p.dynamic && !p.builtin {
+		options = maps.Clone(options)
 	}
 
-	if funcName == "" {
+	if p.funcName == "" {
 		switch t := value.(type) {
 		default:
 			return newFallbackValue(expr), resolutionErr
 		case *ResolvedValue: // the expression has already been resolved before
 			return t, resolutionErr
-		case string:
-			funcName = "string"
-		case float64:
-			funcName = "number"
 		}
 	}
 
-	f, ok := e.template.registry[funcName]
-	if !ok {
-		err = fmt.Errorf(`expression: %w "%s"`, mf2.ErrUnknownFunction, funcName)
+	var result *ResolvedValue
+
+	switch {
+	default:
+		err = fmt.Errorf(`expression: %w "%s"`, mf2.ErrUnknownFunction, p.funcName)
 		return newFallbackValue(expr), errors.Join(resolutionErr, err)
+	case p.f != nil:
+		result, err = p.f(NewResolvedValue(value), options, u.locale)
+	case p.contextFunc != nil:
+		fc := FuncContext{Logger: e.template.logger, Locale: u.locale, Dir: cmp.Or(u.dir, localeDir(u.locale))}
+		result, err = p.contextFunc(e.ctx, fc, NewResolvedValue(value), options)
 	}
 
-	result, err := f(NewResolvedValue(value), options, e.template.locale)
-	if err != nil {
+	if err = errors.Join(uErr, err); err != nil && result == nil {
 		return newFallbackValue(expr), errors.Join(resolutionErr, fmt.Errorf("expression: %w", err))
 	}
 
+	// The copy keeps the operand intact, the function might return it as the result.
+	r := *result
+	r.locale = u.locale
+	r.id = cmp.Or(u.id, r.id)
+
+	switch u.dir {
+	case "ltr", "rtl":
+		r.dir, r.isolate = u.dir, true
+	case "auto":
+		r.dir, r.isolate = "", true
+	}
+
+	result = &r
+
+	if err != nil {
+		// The function has recovered from the error, e.g. bad option replaced with its default value.
+		// The error is kept with the value and reported when the value is used.
+		result.err = errors.Join(result.err, fmt.Errorf("expression: %w", err))
+	}
+
 	return result, resolutionErr
 }
 
+// isolates returns the bidi isolation characters around the formatted value by the template's strategy.
+func (e *executer) isolates(resolved *ResolvedValue) (start, end string) {
+	if e.template.bidiIsolation == BidiIsolationNone {
+		return "", ""
+	}
+
+	switch resolved.dir {
+	default: // unknown direction
+		return "\u2068", "\u2069" // FSI ... PDI
+	case "ltr":
+		if !resolved.isolate && localeDir(e.template.locale) == "ltr" {
+			return "", ""
+		}
+
+		return "\u2066", "\u2069" // LRI ... PDI
+	case "rtl":
+		return "\u2067", "\u2069" // RLI ... PDI
+	}
+}
+
 // resolveValue resolves the value of an expression's operand.
 //
 //   - If the operand is a literal, it returns the literal's value.
@@ -433,7 +726,7 @@ func (e *executer) resolveValue(v ast.Value) (any, error) {
 	case ast.Variable:
 		val, ok := e.variables[string(v)]
 		if !ok {
-			return NewResolvedValue("{" + v.String() + "}"), fmt.Errorf(`%w "%s"`, mf2.ErrUnresolvedVariable, v)
+			return newFallback(v.String()), fmt.Errorf(`%w "%s"`, mf2.ErrUnresolvedVariable, v)
 		}
 
 		return val, val.err
@@ -449,13 +742,83 @@ func (e *executer) resolveOptions(options []ast.Option) (Options, error) {
 			return nil, fmt.Errorf("option: %w", err)
 		}
 
-		m[opt.Identifier.Name] = NewResolvedValue(value)
+		resolved := NewResolvedValue(value)
+
+		// Some options must be set by a literal, e.g. "select" option of :number.
+		// The copy keeps the value of the variable intact.
+		if _, ok := opt.Value.(ast.Variable); ok {
+			v := *resolved
+			v.fromVariable = true
+			resolved = &v
+		}
+
+		m[opt.Identifier.String()] = resolved
 	}
 
 	return m, nil
 }
 
-func (e *executer) resolveMatcher(m ast.Matcher) error {
+// uOptions are the options in the "u" namespace. They apply to any function.
+type uOptions struct {
+	// The locale of the expression, the template's locale by default.
+	locale language.Tag
+	// The identifier of the expression.
+	id string
+	// The direction of the expression (ltr, rtl, auto, inherit).
+	dir string
+}
+
+// resolveUOptions resolves and removes the options in the "u" namespace from the function options.
+// The bad option is reported and ignored. The locale is the default locale of the expression.
+func resolveUOptions(options Options, locale language.Tag) (uOptions, error) {
+	var err error
+
+	u := uOptions{locale: locale}
+
+	for name, value := range options {
+		switch name {
+		default:
+			continue
+		case "u:id":
+			u.id = value.String()
+		case "u:dir":
+			switch dir := value.String(); dir {
+			default:
+				err = errors.Join(err, fmt.Errorf(`%w: u:dir "%s"`, mf2.ErrBadOption, dir))
+			case "ltr", "rtl", "auto", "inherit":
+				u.dir = dir
+			}
+		case "u:locale":
+			locale, parseErr := language.Parse(value.String())
+			if parseErr != nil {
+				err = errors.Join(err, fmt.Errorf(`%w: u:locale "%s"`, mf2.ErrBadOption, value))
+				break
+			}
+
+			u.locale = locale
+		}
+
+		delete(options, name)
+	}
+
+	return u, err
+}
+
+// resolveMarkup validates the markup options. Markup is not formatted,
+// the options u:dir and u:locale are not allowed.
+func resolveMarkup(m ast.Markup) error {
+	var err error
+
+	for _, opt := range m.Options {
+		if name := opt.Identifier.String(); name == "u:dir" || name == "u:locale" {
+			err = errors.Join(err, fmt.Errorf(`markup: %w "%s"`, mf2.ErrBadOption, name))
+		}
+	}
+
+	return err
+}
+
+func (e *executer) resolveMatcher(m *matcherPlan) error {
 	selectors, matcherErr := e.resolveSelectors(m)
 	if matcherErr != nil && !errors.Is(matcherErr, mf2.ErrBadSelector) {
 		return fmt.Errorf("matcher: %w", matcherErr)
@@ -473,15 +836,15 @@ func (e *executer) resolveMatcher(m ast.Matcher) error {
 	return matcherErr
 }
 
-func (e *executer) resolveSelectors(m ast.Matcher) ([]*ResolvedValue, error) {
+func (e *executer) resolveSelectors(m *matcherPlan) ([]*ResolvedValue, error) {
 	var err error
 
-	res := make([]*ResolvedValue, 0, len(m.Selectors))
+	res := make([]*ResolvedValue, 0, len(m.selectors))
 
-	for _, selector := range m.Selectors {
+	for _, selector := range m.selectors {
 		// Selector variable is ALWAYS resolved. Parser errors with ErrBadSelector
 		// when selector has no annotation.
-		v := e.variables[string(selector)]
+		v := e.variables[selector]
 		if v.selectKey == nil {
 			err = errors.Join(err, fmt.Errorf(`%w "%s"`, mf2.ErrBadSelector, v))
 		}
@@ -496,56 +859,29 @@ func (e *executer) resolveSelectors(m ast.Matcher) ([]*ResolvedValue, error) {
 	return res, err
 }
 
-func (e *executer) resolvePreferences(m ast.Matcher, selectors []*ResolvedValue) [][]string {
+func (e *executer) resolvePreferences(m *matcherPlan, selectors []*ResolvedValue) [][]string {
 	// Step 2: Resolve Preferences
 	pref := make([][]string, 0, len(selectors))
 
-	// all variants have the same number of keys
-	n := len(m.Variants[0].Keys)
-
+	// the unique keys of the selectors are collected by [Template.compile]
 	for i := range selectors {
-		keys := make([]string, 0, n)
-
-		for _, variant := range m.Variants {
-			// NOTE(mvilks): since collected keys will be compared to the selector,
-			// we need the keys's raw string value, not the representation of it
-			// e.g. the `1` should be equal to `|1|`
-			var key string
-
-			switch v := variant.Keys[i].(type) {
-			case ast.CatchAllKey:
-				continue
-			case ast.QuotedLiteral:
-				key = string(v)
-			case ast.NameLiteral:
-				key = string(v)
-			}
-
-			// add only unique keys
-			if !slices.Contains(keys, key) {
-				keys = append(keys, key)
-			}
-		}
-
-		matches := matchSelectorKeys(selectors[i], keys)
+		matches := matchSelectorKeys(selectors[i], m.keys[i])
 		pref = append(pref, matches)
 	}
 
 	return pref
 }
 
-func (e *executer) filterVariants(m ast.Matcher, pref [][]string) []ast.Variant {
+func (e *executer) filterVariants(m *matcherPlan, pref [][]string) []*variantPlan {
 	// Step 3: Filter Variants
-	var filteredVariants []ast.Variant
+	var filteredVariants []*variantPlan
 
 variantLoop:
-	for _, variant := range m.Variants {
+	for j := range m.variants {
+		variant := &m.variants[j]
+
 		for i, matchedSelectorKeys := range pref {
-			// NOTE(mvilks): since collected keys will be compared to the selector,
-			//	we need the keys's raw string value, not the representation of it
-			//  e.g. the `1` should be equal to `|1|`
-			_, ok := variant.Keys[i].(ast.CatchAllKey)
-			if !ok && !slices.Contains(matchedSelectorKeys, keyString(variant.Keys[i])) {
+			if key := variant.keys[i]; !key.catchAll && !slices.Contains(matchedSelectorKeys, key.value) {
 				continue variantLoop
 			}
 		}
@@ -556,7 +892,7 @@ variantLoop:
 	return filteredVariants
 }
 
-func (e *executer) bestMatchedPattern(filteredVariants []ast.Variant, pref [][]string) ast.QuotedPattern {
+func (e *executer) bestMatchedPattern(filteredVariants []*variantPlan, pref [][]string) []partPlan {
 	// Step 4: Sort Variants
 	sortable := make([]sortableVariant, 0, len(filteredVariants))
 
@@ -568,23 +904,15 @@ func (e *executer) bestMatchedPattern(filteredVariants []ast.Variant, pref [][]s
 		matches := v
 
 		for tupleIndex, tuple := range sortable {
-			key := tuple.Variant.Keys[i]
+			key := tuple.Variant.keys[i]
 			currentScore := len(matches)
 
-			var ks string
-
-			// NOTE(mvilks): since collected keys will be compared to the selector,
-			//	we need the keys's raw string value, not the representation of it
-			//  e.g. the `1` should be equal to `|1|`
-			switch key := key.(type) {
-			default:
-				ks = keyString(key)
-			case ast.CatchAllKey:
+			if key.catchAll {
 				sortable[tupleIndex].Score = currentScore
 				continue
 			}
 
-			currentScore = slices.Index(matches, ks)
+			currentScore = slices.Index(matches, key.value)
 
 			sortable[tupleIndex].Score = currentScore
 		}
@@ -592,7 +920,7 @@ func (e *executer) bestMatchedPattern(filteredVariants []ast.Variant, pref [][]s
 		sort.Sort(sortableVariants(sortable))
 	}
 
-	return sortable[0].Variant.QuotedPattern
+	return sortable[0].Variant.pattern
 }
 
 func keyString(key ast.VariantKey) string {
@@ -622,7 +950,7 @@ func matchSelectorKeys(selector *ResolvedValue, keys []string) []string {
 }
 
 type sortableVariant struct {
-	Variant ast.Variant
+	Variant *variantPlan
 	Score   int
 }
 
@@ -1,7 +1,14 @@
 package template
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
 	"testing"
 
 	"go.expect.digital/mf2"
@@ -50,6 +57,21 @@ func Test_ExecuteSimpleMessage(t *testing.T) {
 			},
 			want: "Hello, John",
 		},
+		{
+			name: "u:locale option",
+			text: "{ 4.2 :number u:locale=lv } { 4.2 :number }",
+			want: "4,2 4.2",
+		},
+		{
+			name: "u: options are not passed to function",
+			text: "{ :count u:id=x u:dir=rtl u:locale=lv opt=1 }",
+			funcs: Registry{
+				"count": func(_ *ResolvedValue, options Options, _ language.Tag) (*ResolvedValue, error) {
+					return NewResolvedValue(len(options)), nil
+				},
+			},
+			want: "1",
+		},
 	}
 
 	for _, test := range tests {
@@ -181,6 +203,12 @@ func Test_Matcher(t *testing.T) {
 			inputs: []map[string]any{{"foo": "\u0044\u0323\u0307"}},
 			want:   []string{"Right"},
 		},
+		{
+			name:   "exact selection survives re-annotation",
+			text:   ".input {$n :number select=exact} .local $m = {$n :number} .match $m one {{One}} * {{Other}}",
+			inputs: []map[string]any{{"n": 1}},
+			want:   []string{"Other"},
+		},
 	}
 
 	for _, test := range tests {
@@ -214,6 +242,78 @@ func Test_Matcher(t *testing.T) {
 	}
 }
 
+func Test_BidiIsolation(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name, text string
+		locale     language.Tag
+		strategy   BidiIsolation
+		want       string
+	}{
+		{
+			name:     "none",
+			text:     "{world :string u:dir=rtl} {1 :number}",
+			locale:   language.Arabic,
+			strategy: BidiIsolationNone,
+			want:     "world ١",
+		},
+		{
+			name:     "unknown direction",
+			text:     "hello {world}",
+			locale:   language.English,
+			strategy: BidiIsolationDefault,
+			want:     "hello \u2068world\u2069",
+		},
+		{
+			name:     "same direction",
+			text:     "hello {1 :number}",
+			locale:   language.English,
+			strategy: BidiIsolationDefault,
+			want:     "hello 1",
+		},
+		{
+			name:     "opposite direction",
+			text:     "مرحبا {1 :number u:locale=en}",
+			locale:   language.Arabic,
+			strategy: BidiIsolationDefault,
+			want:     "مرحبا \u20661\u2069",
+		},
+		{
+			name:     "u:dir",
+			text:     "{a :string u:dir=ltr} {b :string u:dir=rtl} {c :string u:dir=auto} {d :string u:dir=inherit}",
+			locale:   language.English,
+			strategy: BidiIsolationDefault,
+			want:     "\u2066a\u2069 \u2067b\u2069 \u2068c\u2069 \u2068d\u2069",
+		},
+		{
+			name:     "markup is not isolated",
+			text:     "{#b}bold{/b}",
+			locale:   language.English,
+			strategy: BidiIsolationDefault,
+			want:     "bold",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			template, err := New(WithLocale(test.locale), WithBidiIsolation(test.strategy)).Parse(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := template.Sprint(nil)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if test.want != got {
+				t.Errorf("want '%+q', got '%+q'", test.want, got)
+			}
+		})
+	}
+}
+
 func Test_ExecuteErrors(t *testing.T) {
 	t.Parallel()
 
@@ -263,6 +363,33 @@ func Test_ExecuteErrors(t *testing.T) {
 			text: ".input {$foo :number} .match $foo 1 {{one}} |1| {{also one}} * {{other}}",
 			want: want{parseErr: mf2.ErrDuplicateVariant},
 		},
+		{
+			name:  "select option set by variable",
+			text:  "{1 :number select=$sel}",
+			input: map[string]any{"sel": "exact"},
+			want:  want{execErr: mf2.ErrBadOption, text: "1"},
+		},
+		{
+			name:  "bad selector, select option set by variable",
+			text:  ".local $n = {1 :number select=$sel} .match $n 1 {{one}} * {{other {$n}}}",
+			input: map[string]any{"sel": "exact"},
+			want:  want{execErr: mf2.ErrBadSelector, text: "other 1"},
+		},
+		{
+			name: "bad u:dir option",
+			text: "{ world :string u:dir=up }",
+			want: want{execErr: mf2.ErrBadOption, text: "world"},
+		},
+		{
+			name: "bad u:locale option",
+			text: "{ 4.2 :number u:locale=|not a locale| }",
+			want: want{execErr: mf2.ErrBadOption, text: "4.2"},
+		},
+		{
+			name: "u:dir option on markup",
+			text: "{ #b u:dir=rtl }content{ /b }",
+			want: want{execErr: mf2.ErrBadOption, text: "content"},
+		},
 	}
 
 	for _, test := range tests {
@@ -290,6 +417,162 @@ func Test_ExecuteErrors(t *testing.T) {
 	}
 }
 
+func Test_Inputs(t *testing.T) {
+	t.Parallel()
+
+	if got := New().Inputs(); got != nil {
+		t.Errorf("want nil, got %v", got)
+	}
+
+	template, err := New().Parse(".input {$count :number} .local $n = {$count} {{{$name} has {$n} items}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []string{"count", "name"}, template.Inputs(); !slices.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func Test_Bind(t *testing.T) {
+	t.Parallel()
+
+	template, err := New(WithLocale(language.English)).
+		Parse("{1.5 :number} {$n :number} {2.5 :number u:locale=en} {$n :number u:dir=$dir}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := map[string]any{"n": 3.5, "dir": "ltr"}
+
+	for _, test := range []struct {
+		template *Template
+		want     string
+	}{
+		{template: template.Bind(language.Latvian), want: "1,5 3,5 2.5 3,5"},
+		{template: template, want: "1.5 3.5 2.5 3.5"},
+		{template: template.Bind(language.German).Bind(language.English), want: "1.5 3.5 2.5 3.5"},
+	} {
+		got, err := test.template.Sprint(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.want != got {
+			t.Errorf("want '%s', got '%s'", test.want, got)
+		}
+	}
+
+	parts, err := template.Bind(language.Latvian).FormatToParts(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := language.Latvian, parts[0].Locale; want != got {
+		t.Errorf("want '%s', got '%s'", want, got)
+	}
+}
+
+func Test_ExecuteContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+
+	info := func(ctx context.Context, fc FuncContext, _ *ResolvedValue, _ Options) (*ResolvedValue, error) {
+		fc.Logger.InfoContext(ctx, "info", "locale", fc.Locale)
+		return NewResolvedValue(fmt.Sprintf("%v %s %s", ctx.Value(key{}), fc.Locale, fc.Dir)), nil
+	}
+
+	var logs bytes.Buffer
+
+	template, err := New(
+		WithContextFunc("info", info),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+
+				return a
+			},
+		}))),
+	).Parse("{:info}, {:info u:locale=ar}, {:info u:locale=he u:dir=ltr}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	if err = template.ExecuteContext(context.WithValue(t.Context(), key{}, "value"), &sb, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "value en-US ltr, value ar rtl, value he ltr", sb.String(); want != got {
+		t.Errorf("want '%s', got '%s'", want, got)
+	}
+
+	if want, got := "level=INFO msg=info locale=en-US\n", strings.SplitAfter(logs.String(), "\n")[0]; want != got {
+		t.Errorf("want '%s', got '%s'", want, got)
+	}
+}
+
+func Test_ExecuteContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// the first call cancels the execution
+	cancelFunc := func(context.Context, FuncContext, *ResolvedValue, Options) (*ResolvedValue, error) {
+		calls++
+		cancel()
+
+		return NewResolvedValue(calls), nil
+	}
+
+	template, err := New(WithContextFunc("cancel", cancelFunc)).Parse(".local $x = {:cancel} .local $y = {:cancel} {{{$x} {$y}}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = template.ExecuteContext(ctx, io.Discard, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want '%s', got '%s'", context.Canceled, err)
+	}
+
+	if calls != 1 {
+		t.Errorf("want 1 call, got %d", calls)
+	}
+}
+
+func Test_WithContextFuncReplaced(t *testing.T) {
+	t.Parallel()
+
+	contextFunc := func(context.Context, FuncContext, *ResolvedValue, Options) (*ResolvedValue, error) {
+		return NewResolvedValue("context"), nil
+	}
+
+	f := func(*ResolvedValue, Options, language.Tag) (*ResolvedValue, error) {
+		return NewResolvedValue("func"), nil
+	}
+
+	for want, options := range map[string][]Option{
+		"func":    {WithContextFunc("f", contextFunc), WithFunc("f", f)},
+		"context": {WithFuncs(Registry{"f": f}), WithContextFunc("f", contextFunc)},
+	} {
+		template, err := New(options...).Parse("{:f}")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := template.Sprint(nil); err != nil || want != got {
+			t.Errorf("want '%s', got '%s' (%v)", want, got, err)
+		}
+	}
+}
+
 func BenchmarkTemplate_Sprint(b *testing.B) {
 	//nolint:dupword
 	tmpl, err := New().Parse(`.input {$foo :string}
@@ -310,6 +593,8 @@ one * {{one other}}
 
 	var result string
 
+	b.ReportAllocs()
+
 	for range b.N {
 		result, _ = tmpl.Sprint(map[string]any{"foo": "foo", "bar": 1})
 	}